@@ -0,0 +1,69 @@
+package ini
+
+import (
+	"bufio"
+	"io"
+)
+
+// Decoder reads and parses an ini document from an input stream. The input is parsed
+// incrementally, as it is being read, without buffering the whole document in memory.
+type Decoder struct {
+	r             *bufio.Reader
+	opts          ParseOptions
+	unmarshalOpts UnmarshalOptions
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+
+	return &Decoder{
+		r: br,
+	}
+}
+
+// Sets the options used when parsing the input stream
+func (dec *Decoder) SetParseOptions(opts ParseOptions) {
+	dec.opts = opts
+}
+
+// Sets the options used by `Decode` when unmarshaling the parsed document, the `Parse`
+// field is ignored, use `SetParseOptions` instead
+func (dec *Decoder) SetUnmarshalOptions(opts UnmarshalOptions) {
+	dec.unmarshalOpts = opts
+}
+
+// Causes `Decode` to return an error when the document contains keys or sections that
+// don't match any field of the destination struct
+func (dec *Decoder) DisallowUnknownFields() {
+	dec.unmarshalOpts.DisallowUnknown = true
+}
+
+// Reads the input stream until EOF and returns the parsed document
+func (dec *Decoder) DecodeDoc() (*IniDoc, error) {
+	p := newParser(dec.opts)
+
+	for {
+		char, _, err := dec.r.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.feed(char)
+	}
+
+	return p.finish()
+}
+
+// Reads the input stream until EOF and stores the result in the value pointed to by [v]
+func (dec *Decoder) Decode(v any) error {
+	doc, err := dec.DecodeDoc()
+	if err != nil {
+		return err
+	}
+	return UnmarshalDocWithOptions(doc, v, dec.unmarshalOpts)
+}
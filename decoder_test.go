@@ -0,0 +1,60 @@
+package ini_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ncpa0cpl/ini"
+)
+
+func TestDecoder(t *testing.T) {
+	expect := expect(t)
+
+	docStr := `
+k=v
+k1=2
+k2=2.2
+k3=3
+
+[user]
+name=tomàš
+age=-23
+`
+
+	cfg := TestConfig{}
+	dec := ini.NewDecoder(iotest.OneByteReader(strings.NewReader(docStr)))
+	expect(dec.Decode(&cfg)).NoErr()
+
+	expect(cfg.K).ToBe("v")
+	expect(cfg.K1).ToBe(int(2))
+	expect(cfg.K2).ToBe(float64(2.2))
+	expect(cfg.K3).ToBe(int64(3))
+	expect(cfg.User.Name).ToBe("tomàš")
+	expect(cfg.User.Age).ToBe(int(-23))
+}
+
+func TestDecoderParseOptions(t *testing.T) {
+	expect := expect(t)
+
+	dec := ini.NewDecoder(strings.NewReader("k=v\n[broken\n"))
+	dec.SetParseOptions(ini.ParseOptions{Strict: true})
+
+	_, err := dec.DecodeDoc()
+
+	var parseErr *ini.ParseError
+	expect(errors.As(err, &parseErr)).ToBe(true)
+	expect(parseErr.Line).ToBe(2)
+	expect(parseErr.Reason).ToBe(ini.ParseErrUnterminatedSection)
+}
+
+func TestDecoderReadError(t *testing.T) {
+	expect := expect(t)
+
+	readErr := errors.New("connection reset")
+	dec := ini.NewDecoder(iotest.ErrReader(readErr))
+
+	_, err := dec.DecodeDoc()
+	expect(err).ToBe(readErr)
+}
@@ -0,0 +1,233 @@
+package ini
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ChangeKind int
+
+const (
+	ChangeAdded ChangeKind = iota + 1
+	ChangeRemoved
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	}
+	return fmt.Sprintf("unknown change (%d)", int(k))
+}
+
+// Describes a single difference between two documents
+type Change struct {
+	Kind ChangeKind
+	// Full path of the section, empty for top level keys
+	Section string
+	// Key of the changed value, empty if the change is about the section itself
+	// (section was added, removed or it's comment was modified)
+	Key        string
+	OldValue   string
+	NewValue   string
+	OldComment string
+	NewComment string
+}
+
+// Returns the list of changes needed to get from the document [a] to the document [b].
+//
+// Top level keys are listed first, followed by the sections, in the order in which they
+// appear in [a], with keys and sections that exist only in [b] listed after those, in the
+// order in which they appear in [b]. Adding or removing a section is reported as a change
+// of the section followed by a change for each of it's keys. Sections without any lines
+// are treated as non-existent, since those are not serialized.
+//
+// Keys and section names are compared according to the options of the document [a].
+// Repeated keys (see `GetAll`) are compared occurrence by occurrence. A nil document is
+// treated as an empty one.
+func Diff(a, b *IniDoc) []Change {
+	if b == nil {
+		b = NewDoc()
+	}
+	if a == nil {
+		a = NewDocWithOptions(b.opts)
+	}
+
+	changes := a.diffLines("", a.lines, b.lines, nil)
+
+	names := make([]string, 0, len(a.sections)+len(b.sections))
+	for _, section := range a.sections {
+		names = a.appendUniqueSection(names, section.name)
+	}
+	for _, section := range b.sections {
+		names = a.appendUniqueSection(names, section.name)
+	}
+
+	for _, name := range names {
+		sectA := a.findSection(name)
+		sectB := a.findSectionIn(b, name)
+
+		existsA := sectA != nil && len(sectA.lines) > 0
+		existsB := sectB != nil && len(sectB.lines) > 0
+
+		switch {
+		case existsA && existsB:
+			if sectA.comment != sectB.comment {
+				changes = append(changes, Change{
+					Kind:       ChangeModified,
+					Section:    name,
+					OldComment: sectA.comment,
+					NewComment: sectB.comment,
+				})
+			}
+			changes = a.diffLines(name, sectA.lines, sectB.lines, changes)
+		case existsA:
+			changes = append(changes, Change{
+				Kind:       ChangeRemoved,
+				Section:    name,
+				OldComment: sectA.comment,
+			})
+			changes = a.diffLines(name, sectA.lines, nil, changes)
+		case existsB:
+			changes = append(changes, Change{
+				Kind:       ChangeAdded,
+				Section:    name,
+				NewComment: sectB.comment,
+			})
+			changes = a.diffLines(name, nil, sectB.lines, changes)
+		}
+	}
+
+	return changes
+}
+
+func (d *IniDoc) diffLines(section string, a []iniLine, b []iniLine, changes []Change) []Change {
+	occurrences := make(map[string]int)
+	for _, lineA := range a {
+		if lineA.lineType != lineTypeKv {
+			continue
+		}
+
+		index := occurrences[d.keyID(lineA.key)]
+		occurrences[d.keyID(lineA.key)]++
+
+		lineB := d.findLine(b, lineA.key, index)
+		if lineB == nil {
+			changes = append(changes, Change{
+				Kind:       ChangeRemoved,
+				Section:    section,
+				Key:        lineA.key,
+				OldValue:   lineA.value,
+				OldComment: lineA.comment,
+			})
+		} else if lineA.value != lineB.value || lineA.comment != lineB.comment {
+			changes = append(changes, Change{
+				Kind:       ChangeModified,
+				Section:    section,
+				Key:        lineA.key,
+				OldValue:   lineA.value,
+				NewValue:   lineB.value,
+				OldComment: lineA.comment,
+				NewComment: lineB.comment,
+			})
+		}
+	}
+
+	clear(occurrences)
+	for _, lineB := range b {
+		if lineB.lineType != lineTypeKv {
+			continue
+		}
+
+		index := occurrences[d.keyID(lineB.key)]
+		occurrences[d.keyID(lineB.key)]++
+
+		if d.findLine(a, lineB.key, index) == nil {
+			changes = append(changes, Change{
+				Kind:       ChangeAdded,
+				Section:    section,
+				Key:        lineB.key,
+				NewValue:   lineB.value,
+				NewComment: lineB.comment,
+			})
+		}
+	}
+
+	return changes
+}
+
+// Returns the first section of [doc] with the given name, section names are compared
+// according to the options of [d]
+func (d *IniDoc) findSectionIn(doc *IniDoc, name string) *IniSection {
+	for _, section := range doc.sections {
+		if d.sectionsEqual(section.name, name) {
+			return section
+		}
+	}
+	return nil
+}
+
+func (d *IniDoc) appendUniqueSection(names []string, name string) []string {
+	for _, n := range names {
+		if d.sectionsEqual(n, name) {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+// Renders the given changes in a format similar to the unified diff, e.x.:
+//
+//	 [server]
+//	-port=80
+//	+port=8080
+//	+[server.tls]
+//	+cert=/etc/cert.pem
+func FormatDiff(changes []Change) string {
+	var sb strings.Builder
+	w := newIniWriter(&sb)
+
+	currentSection := ""
+	for _, change := range changes {
+		if change.Key == "" {
+			// section was added, removed or it's comment changed
+			currentSection = change.Section
+			if change.Kind != ChangeAdded && change.OldComment != "" {
+				writeCommentLines(w, "-; ", change.OldComment)
+			}
+			if change.Kind != ChangeRemoved && change.NewComment != "" {
+				writeCommentLines(w, "+; ", change.NewComment)
+			}
+			switch change.Kind {
+			case ChangeAdded:
+				w.writeString("+[", change.Section, "]\n")
+			case ChangeRemoved:
+				w.writeString("-[", change.Section, "]\n")
+			case ChangeModified:
+				w.writeString(" [", change.Section, "]\n")
+			}
+			continue
+		}
+
+		if change.Section != currentSection {
+			currentSection = change.Section
+			w.writeString(" [", change.Section, "]\n")
+		}
+
+		if change.Kind == ChangeRemoved || change.Kind == ChangeModified {
+			w.writeString("-")
+			(&iniLine{lineType: lineTypeKv, key: change.Key, value: change.OldValue, comment: change.OldComment}).writeTo(w)
+		}
+		if change.Kind == ChangeAdded || change.Kind == ChangeModified {
+			w.writeString("+")
+			(&iniLine{lineType: lineTypeKv, key: change.Key, value: change.NewValue, comment: change.NewComment}).writeTo(w)
+		}
+	}
+
+	return sb.String()
+}
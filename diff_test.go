@@ -0,0 +1,123 @@
+package ini_test
+
+import (
+	"testing"
+
+	"github.com/ncpa0cpl/ini"
+)
+
+func TestDiff(t *testing.T) {
+	expect := expect(t)
+
+	a := ini.Parse(`name=app
+debug=true
+
+[server]
+host=localhost
+port=80
+
+[server.tls]
+cert=/etc/cert.pem
+
+[old]
+k=v
+`)
+	b := ini.Parse(`name=app
+debug=false
+workers=4
+
+; server settings
+[server]
+host=localhost ; only local connections
+port=8080
+
+[new]
+k=v
+`)
+
+	changes := ini.Diff(a, b)
+
+	expect(changes).ToBe([]ini.Change{
+		{Kind: ini.ChangeModified, Key: "debug", OldValue: "true", NewValue: "false"},
+		{Kind: ini.ChangeAdded, Key: "workers", NewValue: "4"},
+		{Kind: ini.ChangeModified, Section: "server", NewComment: "server settings"},
+		{Kind: ini.ChangeModified, Section: "server", Key: "host", OldValue: "localhost", NewValue: "localhost", NewComment: "only local connections"},
+		{Kind: ini.ChangeModified, Section: "server", Key: "port", OldValue: "80", NewValue: "8080"},
+		{Kind: ini.ChangeRemoved, Section: "server.tls"},
+		{Kind: ini.ChangeRemoved, Section: "server.tls", Key: "cert", OldValue: "/etc/cert.pem"},
+		{Kind: ini.ChangeRemoved, Section: "old"},
+		{Kind: ini.ChangeRemoved, Section: "old", Key: "k", OldValue: "v"},
+		{Kind: ini.ChangeAdded, Section: "new"},
+		{Kind: ini.ChangeAdded, Section: "new", Key: "k", NewValue: "v"},
+	})
+
+	expect(ini.FormatDiff(changes)).ToBe(`-debug=true
++debug=false
++workers=4
++; server settings
+ [server]
+-host=localhost
++host=localhost ; only local connections
+-port=80
++port=8080
+-[server.tls]
+-cert=/etc/cert.pem
+-[old]
+-k=v
++[new]
++k=v
+`)
+
+	expect(len(ini.Diff(a, a))).ToBe(0)
+}
+
+func TestDiffCaseInsensitive(t *testing.T) {
+	expect := expect(t)
+
+	opts := ini.ParseOptions{DocOptions: ini.DocOptions{CaseInsensitiveKeys: true, CaseInsensitiveSections: true}}
+
+	a, err := ini.ParseWithOptions("Level=info\n\n[DB]\nPort=5432\n", opts)
+	expect(err).NoErr()
+	b, err := ini.ParseWithOptions("level=info\n\n[db]\nport=6543\n", opts)
+	expect(err).NoErr()
+
+	expect(ini.Diff(a, b)).ToBe([]ini.Change{
+		{Kind: ini.ChangeModified, Section: "DB", Key: "Port", OldValue: "5432", NewValue: "6543"},
+	})
+}
+
+func TestDiffRepeatedKeys(t *testing.T) {
+	expect := expect(t)
+
+	opts := ini.ParseOptions{DuplicateKeys: ini.DuplicateKeysKeepAll}
+
+	a, err := ini.ParseWithOptions("host=a\nhost=b\nport=80\nport=443\n", opts)
+	expect(err).NoErr()
+	b, err := ini.ParseWithOptions("host=a\nhost=c\nhost=d\nport=80\n", opts)
+	expect(err).NoErr()
+
+	expect(ini.Diff(a, b)).ToBe([]ini.Change{
+		{Kind: ini.ChangeModified, Key: "host", OldValue: "b", NewValue: "c"},
+		{Kind: ini.ChangeRemoved, Key: "port", OldValue: "443"},
+		{Kind: ini.ChangeAdded, Key: "host", NewValue: "d"},
+	})
+	expect(len(ini.Diff(a, a))).ToBe(0)
+}
+
+func TestDiffNil(t *testing.T) {
+	expect := expect(t)
+
+	doc := ini.Parse("k=v\n\n[s]\na=1\n")
+
+	expect(ini.Diff(doc, nil)).ToBe([]ini.Change{
+		{Kind: ini.ChangeRemoved, Key: "k", OldValue: "v"},
+		{Kind: ini.ChangeRemoved, Section: "s"},
+		{Kind: ini.ChangeRemoved, Section: "s", Key: "a", OldValue: "1"},
+	})
+	expect(ini.Diff(nil, doc)).ToBe([]ini.Change{
+		{Kind: ini.ChangeAdded, Key: "k", NewValue: "v"},
+		{Kind: ini.ChangeAdded, Section: "s"},
+		{Kind: ini.ChangeAdded, Section: "s", Key: "a", NewValue: "1"},
+	})
+	expect(len(ini.Diff(nil, nil))).ToBe(0)
+}
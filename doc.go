@@ -2,9 +2,11 @@ package ini
 
 import (
 	"fmt"
+	"io"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -24,6 +26,8 @@ type iniLine struct {
 	key      string
 	value    string
 	comment  string
+	// file the value was loaded from, if empty the value comes from the file of the document
+	source string
 }
 
 type IniSection struct {
@@ -36,13 +40,73 @@ type IniSection struct {
 type IniDoc struct {
 	lines    []iniLine
 	sections []*IniSection
+	// file the document was loaded from
+	source string
+	opts   DocOptions
+}
+
+// Controls how keys and section names are matched and which delimiter is written
+// between keys and values when the document is serialized. The original spelling of
+// keys and section names is always kept.
+type DocOptions struct {
+	// Keys differing only in letter case are treated as the same key
+	CaseInsensitiveKeys bool
+	// Section names differing only in letter case are treated as the same section
+	CaseInsensitiveSections bool
+	// Written between keys and values when serializing, `=` if empty (e.x. `: `, ` = `)
+	Delimiter string
 }
 
 func NewDoc() *IniDoc {
+	return NewDocWithOptions(DocOptions{})
+}
+
+func NewDocWithOptions(opts DocOptions) *IniDoc {
 	return &IniDoc{
 		lines:    make([]iniLine, 0, 16),
 		sections: make([]*IniSection, 0, 16),
+		opts:     opts,
+	}
+}
+
+// Returns the options of the document
+func (d *IniDoc) Options() DocOptions {
+	return d.opts
+}
+
+// Returns the key in the form used for comparing keys
+func (d *IniDoc) keyID(key string) string {
+	if d != nil && d.opts.CaseInsensitiveKeys {
+		return strings.ToLower(key)
+	}
+	return key
+}
+
+// Returns the section name in the form used for comparing section names
+func (d *IniDoc) sectionID(name string) string {
+	if d != nil && d.opts.CaseInsensitiveSections {
+		return strings.ToLower(name)
 	}
+	return name
+}
+
+func (d *IniDoc) keysEqual(a, b string) bool {
+	if d != nil && d.opts.CaseInsensitiveKeys {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+func (d *IniDoc) sectionsEqual(a, b string) bool {
+	if d != nil && d.opts.CaseInsensitiveSections {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// Reports whether [name] is a subsection of the [parent] section, to any level deep
+func (d *IniDoc) isSubsection(name, parent string) bool {
+	return len(name) > len(parent) && name[len(parent)] == '.' && d.sectionsEqual(name[:len(parent)], parent)
 }
 
 func NewSection() *IniSection {
@@ -53,7 +117,7 @@ func NewSection() *IniSection {
 
 func (d *IniDoc) createSectionIfNotExist(sectionName string) {
 	for _, sect := range d.sections {
-		if sect.name == sectionName {
+		if d.sectionsEqual(sect.name, sectionName) {
 			return
 		}
 	}
@@ -75,7 +139,7 @@ func (d *IniDoc) putSection(section *IniSection) {
 				added := false
 				subSection.name = fmt.Sprintf("%s.%s", section.name, subSection.name)
 				for idx, dsection := range d.sections {
-					if dsection.name == subSection.name {
+					if d.sectionsEqual(dsection.name, subSection.name) {
 						d.sections[idx] = subSection
 						added = true
 						break
@@ -84,12 +148,14 @@ func (d *IniDoc) putSection(section *IniSection) {
 				if !added {
 					d.sections = append(d.sections, subSection)
 				}
+				subSection.root = d
 			}
 		}
+		section.root = d
 	}()
 
 	for idx, dsection := range d.sections {
-		if dsection.name == section.name {
+		if d.sectionsEqual(dsection.name, section.name) {
 			d.sections[idx] = section
 			return
 		}
@@ -99,7 +165,7 @@ func (d *IniDoc) putSection(section *IniSection) {
 
 func (d *IniDoc) getField(key string) *iniLine {
 	for idx := range d.lines {
-		if d.lines[idx].lineType == lineTypeKv && d.lines[idx].key == key {
+		if d.lines[idx].lineType == lineTypeKv && d.keysEqual(d.lines[idx].key, key) {
 			return &d.lines[idx]
 		}
 	}
@@ -121,7 +187,9 @@ func (d *IniDoc) lastLine() *iniLine {
 	return &d.lines[len(d.lines)-1]
 }
 
-func (d *IniDoc) addParsedSection(name string) *IniSection {
+// Adds a section found by the parser. If [appendNew] is true a new section is added even
+// if a section with the same name already exists, otherwise the existing one is reused.
+func (d *IniDoc) addParsedSection(name string, appendNew bool) *IniSection {
 	comment := ""
 
 	if len(d.sections) > 0 {
@@ -139,7 +207,12 @@ func (d *IniDoc) addParsedSection(name string) *IniSection {
 		}
 	}
 
-	s := d.Section(name)
+	var s *IniSection
+	if appendNew {
+		s = d.appendSection(name)
+	} else {
+		s = d.Section(name)
+	}
 	s.comment = comment
 
 	return s
@@ -183,7 +256,7 @@ func (d *IniDoc) AddHashComment(value string) {
 // Remove the key-value pair from the document root
 func (d *IniDoc) Del(key string) {
 	for idx := range d.lines {
-		if d.lines[idx].lineType == lineTypeKv && d.lines[idx].key == key {
+		if d.lines[idx].lineType == lineTypeKv && d.keysEqual(d.lines[idx].key, key) {
 			d.lines = slices.Delete(d.lines, idx, idx+1)
 			return
 		}
@@ -198,6 +271,16 @@ func (d *IniDoc) SetFieldComment(fieldKey string, value string) {
 	}
 }
 
+// Sets the comment of the last key-value pair with the given key
+func (d *IniDoc) setLastFieldComment(fieldKey string, value string) {
+	for idx := len(d.lines) - 1; idx >= 0; idx-- {
+		if d.lines[idx].lineType == lineTypeKv && d.keysEqual(d.lines[idx].key, fieldKey) {
+			d.lines[idx].comment = value
+			return
+		}
+	}
+}
+
 func (d *IniDoc) Set(key, value string) {
 	value = strings.Trim(value, " ")
 	if isKeyValid(key) {
@@ -206,6 +289,7 @@ func (d *IniDoc) Set(key, value string) {
 			d.addField(key, value)
 		} else {
 			f.value = value
+			f.source = ""
 		}
 	}
 }
@@ -230,6 +314,16 @@ func (d *IniDoc) SetBool(key string, value bool) {
 	d.Set(key, strVal)
 }
 
+// Sets the duration in the `time.Duration.String` format (e.x. `1m30s`)
+func (d *IniDoc) SetDuration(key string, value time.Duration) {
+	d.Set(key, value.String())
+}
+
+// Sets the time formatted with the given layout, RFC 3339 is used if the layout is empty
+func (d *IniDoc) SetTime(key string, value time.Time, layout string) {
+	d.Set(key, formatTime(value, layout))
+}
+
 // Returns the current comment that's associated with the given property key
 func (d *IniDoc) GetComment(key string) string {
 	f := d.getField(key)
@@ -240,6 +334,30 @@ func (d *IniDoc) GetComment(key string) string {
 	}
 }
 
+// Returns the values of all key-value pairs with the given key, in the order they
+// appear in the document
+func (d *IniDoc) GetAll(key string) []string {
+	values := make([]string, 0, 1)
+	for idx := range d.lines {
+		if d.lines[idx].lineType == lineTypeKv && d.keysEqual(d.lines[idx].key, key) {
+			values = append(values, d.lines[idx].value)
+		}
+	}
+	return values
+}
+
+// Adds a key-value pair, even if a pair with the same key already exists
+func (d *IniDoc) AddValue(key, value string) {
+	if isKeyValid(key) {
+		d.addField(key, strings.Trim(value, " "))
+	}
+}
+
+// Returns true if a key-value pair with the given key exists
+func (d *IniDoc) Has(key string) bool {
+	return d.getField(key) != nil
+}
+
 func (d *IniDoc) Get(key string) string {
 	f := d.getField(key)
 	if f == nil {
@@ -281,13 +399,81 @@ func (d *IniDoc) GetBool(key string) (bool, error) {
 	return strconv.ParseBool(v)
 }
 
-// Retrieves the given section, if that section does not exist it will be added
-func (d *IniDoc) Section(sectionName string) *IniSection {
+// Parses the value as a duration (e.x. `30s`, `1h15m`), see `time.ParseDuration`
+func (d *IniDoc) GetDuration(key string) (time.Duration, error) {
+	v := d.Get(key)
+	if v == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// Parses the value as a time in the given layout, RFC 3339 is used if the layout is empty
+func (d *IniDoc) GetTime(key string, layout string) (time.Time, error) {
+	return parseTime(d.Get(key), layout)
+}
+
+// Returns the name of the file the value of the given key was loaded from. Empty string
+// is returned if the key does not exist or it's value was not loaded from a file.
+func (d *IniDoc) Source(key string) string {
+	f := d.getField(key)
+	if f == nil {
+		return ""
+	}
+	if f.source != "" {
+		return f.source
+	}
+	return d.source
+}
+
+// Returns true if the given section exists
+func (d *IniDoc) HasSection(sectionName string) bool {
+	return d.findSection(sectionName) != nil
+}
+
+func (d *IniDoc) findSection(sectionName string) *IniSection {
 	for _, dsection := range d.sections {
-		if dsection.name == sectionName {
+		if d.sectionsEqual(dsection.name, sectionName) {
 			return dsection
 		}
 	}
+	return nil
+}
+
+// Returns all sections with the given name, in the order they appear in the document.
+// A document contains several sections with the same name only if it was parsed with
+// the `DuplicateSectionsSeparate` option, or if those were added by `Marshal`.
+func (d *IniDoc) Sections(sectionName string) []*IniSection {
+	sections := make([]*IniSection, 0, 1)
+	for _, dsection := range d.sections {
+		if d.sectionsEqual(dsection.name, sectionName) {
+			sections = append(sections, dsection)
+		}
+	}
+	return sections
+}
+
+// Adds a new section, even if a section with the same name already exists
+func (d *IniDoc) appendSection(sectionName string) *IniSection {
+	if d.findSection(sectionName) == nil {
+		return d.Section(sectionName)
+	}
+
+	section := &IniSection{
+		root:  d,
+		name:  sectionName,
+		lines: []iniLine{},
+	}
+	d.sections = append(d.sections, section)
+
+	return section
+}
+
+// Retrieves the given section, if that section does not exist it will be added
+func (d *IniDoc) Section(sectionName string) *IniSection {
+	if dsection := d.findSection(sectionName); dsection != nil {
+		return dsection
+	}
 
 	section := IniSection{
 		root:  d,
@@ -381,7 +567,7 @@ func (d *IniSection) putSubSection(section *IniSection) {
 
 func (d *IniSection) getField(key string) *iniLine {
 	for idx := range d.lines {
-		if d.lines[idx].lineType == lineTypeKv && d.lines[idx].key == key {
+		if d.lines[idx].lineType == lineTypeKv && d.root.keysEqual(d.lines[idx].key, key) {
 			return &d.lines[idx]
 		}
 	}
@@ -441,7 +627,7 @@ func (d *IniSection) AddHashComment(value string) {
 // Remove the key-value pair from this section
 func (d *IniSection) Del(key string) {
 	for idx := range d.lines {
-		if d.lines[idx].lineType == lineTypeKv && d.lines[idx].key == key {
+		if d.lines[idx].lineType == lineTypeKv && d.root.keysEqual(d.lines[idx].key, key) {
 			d.lines = slices.Delete(d.lines, idx, idx+1)
 			return
 		}
@@ -456,6 +642,16 @@ func (d *IniSection) SetFieldComment(fieldKey string, value string) {
 	}
 }
 
+// Sets the comment of the last key-value pair with the given key
+func (d *IniSection) setLastFieldComment(fieldKey string, value string) {
+	for idx := len(d.lines) - 1; idx >= 0; idx-- {
+		if d.lines[idx].lineType == lineTypeKv && d.root.keysEqual(d.lines[idx].key, fieldKey) {
+			d.lines[idx].comment = value
+			return
+		}
+	}
+}
+
 func (d *IniSection) Set(key, value string) {
 	value = strings.Trim(value, " ")
 	if isKeyValid(key) {
@@ -464,6 +660,7 @@ func (d *IniSection) Set(key, value string) {
 			d.addField(key, value)
 		} else {
 			f.value = value
+			f.source = ""
 		}
 	}
 }
@@ -488,6 +685,16 @@ func (d *IniSection) SetBool(key string, value bool) {
 	d.Set(key, strVal)
 }
 
+// Sets the duration in the `time.Duration.String` format (e.x. `1m30s`)
+func (d *IniSection) SetDuration(key string, value time.Duration) {
+	d.Set(key, value.String())
+}
+
+// Sets the time formatted with the given layout, RFC 3339 is used if the layout is empty
+func (d *IniSection) SetTime(key string, value time.Time, layout string) {
+	d.Set(key, formatTime(value, layout))
+}
+
 // Returns the comment associated with this Section. This is the comment right above the
 // section name.
 func (d *IniSection) GetSectionComment() string {
@@ -510,6 +717,30 @@ func (d *IniSection) GetComment(key string) string {
 	}
 }
 
+// Returns the values of all key-value pairs with the given key, in the order they
+// appear in the document
+func (d *IniSection) GetAll(key string) []string {
+	values := make([]string, 0, 1)
+	for idx := range d.lines {
+		if d.lines[idx].lineType == lineTypeKv && d.root.keysEqual(d.lines[idx].key, key) {
+			values = append(values, d.lines[idx].value)
+		}
+	}
+	return values
+}
+
+// Adds a key-value pair, even if a pair with the same key already exists
+func (d *IniSection) AddValue(key, value string) {
+	if isKeyValid(key) {
+		d.addField(key, strings.Trim(value, " "))
+	}
+}
+
+// Returns true if a key-value pair with the given key exists
+func (d *IniSection) Has(key string) bool {
+	return d.getField(key) != nil
+}
+
 func (d *IniSection) Get(key string) string {
 	f := d.getField(key)
 	if f == nil {
@@ -551,6 +782,46 @@ func (d *IniSection) GetBool(key string) (bool, error) {
 	return strconv.ParseBool(v)
 }
 
+// Parses the value as a duration (e.x. `30s`, `1h15m`), see `time.ParseDuration`
+func (d *IniSection) GetDuration(key string) (time.Duration, error) {
+	v := d.Get(key)
+	if v == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// Parses the value as a time in the given layout, RFC 3339 is used if the layout is empty
+func (d *IniSection) GetTime(key string, layout string) (time.Time, error) {
+	return parseTime(d.Get(key), layout)
+}
+
+// Returns the name of the file the value of the given key was loaded from. Empty string
+// is returned if the key does not exist or it's value was not loaded from a file.
+func (d *IniSection) Source(key string) string {
+	f := d.getField(key)
+	if f == nil {
+		return ""
+	}
+	if f.source != "" || d.root == nil {
+		return f.source
+	}
+	return d.root.source
+}
+
+// Returns true if the given sub-section exists
+func (d *IniSection) HasSection(sectionName string) bool {
+	if d.root == nil {
+		return false
+	}
+
+	if d.name != "" {
+		return d.root.HasSection(fmt.Sprintf("%s.%s", d.name, sectionName))
+	}
+
+	return d.root.HasSection(sectionName)
+}
+
 // Retrieves the given sub-section, if that sub-section does not exist it will be added
 func (d *IniSection) Section(sectionName string) *IniSection {
 	if d.root == nil {
@@ -594,13 +865,13 @@ func (d *IniSection) SubsectionNames(includeSubsections ...bool) []string {
 	result := make([]string, 0, len(allSectionNames))
 	if len(includeSubsections) > 0 && includeSubsections[0] {
 		for _, sectName := range allSectionNames {
-			if strings.HasPrefix(sectName, d.name+".") {
+			if d.root.isSubsection(sectName, d.name) {
 				result = append(result, sectName[len(d.name)+1:])
 			}
 		}
 	} else {
 		for _, sectName := range allSectionNames {
-			if strings.HasPrefix(sectName, d.name+".") {
+			if d.root.isSubsection(sectName, d.name) {
 				if !strings.Contains(sectName[len(d.name)+1:], ".") {
 					result = append(result, sectName[len(d.name)+1:])
 				}
@@ -649,7 +920,7 @@ func (d *IniSection) SetName(newName string) {
 
 	// update subsection names
 	for _, section := range d.root.sections {
-		if strings.HasPrefix(section.name, d.name+".") {
+		if d.root.isSubsection(section.name, d.name) {
 			section.name = newName + section.name[len(d.name):]
 		}
 	}
@@ -657,16 +928,16 @@ func (d *IniSection) SetName(newName string) {
 	d.name = newName
 }
 
-func (d *IniSection) addParsedSection(name string) *IniSection {
+func (d *IniSection) addParsedSection(name string, appendNew bool) *IniSection {
 	if d.root == nil {
 		d.root = &IniDoc{}
 	}
 
 	if d.name != "" {
-		return d.root.addParsedSection(fmt.Sprintf("%s.%s", d.name, name))
+		return d.root.addParsedSection(fmt.Sprintf("%s.%s", d.name, name), appendNew)
 	}
 
-	return d.root.addParsedSection(name)
+	return d.root.addParsedSection(name, appendNew)
 
 	// lastLine := d.root.lastLine()
 	// if d.root == from && lastLine != nil && (lastLine.lineType == lineTypeComment || lastLine.lineType == lineTypeHashComment) {
@@ -704,87 +975,215 @@ func escapeIniValue(value string) string {
 	return string(escapedV)
 }
 
-func (f *iniLine) ToString() string {
-	var v string = ""
+// Escapes the characters of the [delimiter] in the key, so that the key is not split
+// when parsed. Whitespace is only escaped if the delimiter consists of whitespace alone,
+// since whitespace around other delimiters is ignored.
+func escapeIniKey(key string, delimiter string) string {
+	special := strings.Trim(delimiter, " \t")
+	if special == "" {
+		special = delimiter
+	}
+	if !strings.ContainsAny(key, special) {
+		return key
+	}
+
+	escapedK := make([]rune, 0, len(key)+4)
+	for _, char := range key {
+		if strings.ContainsRune(special, char) {
+			escapedK = append(escapedK, '\\')
+		}
+		escapedK = append(escapedK, char)
+	}
+
+	return string(escapedK)
+}
+
+func (f *iniLine) writeTo(w *iniWriter) {
 	switch f.lineType {
 	case lineTypeKv:
-		v = fmt.Sprintf("%s=%s", f.key, escapeIniValue(f.value))
+		delimiter := w.delimiter
+		if delimiter == "" {
+			delimiter = "="
+		}
+		w.writeString(escapeIniKey(f.key, delimiter), delimiter, escapeIniValue(f.value))
 		if f.comment != "" {
-			v += fmt.Sprintf(" ; %s", f.comment)
+			w.writeString(" ; ", f.comment)
 		}
-		return v + "\n"
+		w.writeString("\n")
+		return
 	case lineTypeComment:
-		lines := strings.Split(f.value, "\n")
-		for _, line := range lines {
-			v += fmt.Sprintf("; %s\n", line)
-		}
-		return v
+		writeCommentLines(w, "; ", f.value)
+		return
 	case lineTypeHashComment:
-		lines := strings.Split(f.value, "\n")
-		for _, line := range lines {
-			v += fmt.Sprintf("# %s\n", line)
-		}
-		return v
+		writeCommentLines(w, "# ", f.value)
+		return
 	case lineTypeWhiteLine:
-		v += "\n"
-		return v
+		w.writeString("\n")
+		return
 	}
 
 	panic("invalid line type: " + strconv.FormatInt(int64(f.lineType), 10))
 }
 
-func (s *IniSection) ToString() string {
+func writeCommentLines(w *iniWriter, prefix string, comment string) {
+	for line := range strings.SplitSeq(comment, "\n") {
+		w.writeString(prefix, line, "\n")
+	}
+}
+
+func (f *iniLine) ToString() string {
+	var sb strings.Builder
+	f.writeTo(newIniWriter(&sb))
+	return sb.String()
+}
+
+func (s *IniSection) writeTo(w *iniWriter) {
 	if len(s.lines) == 0 {
-		return ""
+		return
 	}
 
-	var v string = ""
+	if s.root != nil {
+		w.delimiter = s.root.opts.Delimiter
+	}
 
 	if s.comment != "" {
-		lines := strings.Split(s.comment, "\n")
-		for _, line := range lines {
-			v += fmt.Sprintf("; %s\n", line)
-		}
+		writeCommentLines(w, "; ", s.comment)
 	}
 
-	v += fmt.Sprintf("[%s]\n", s.name)
+	w.writeString("[", s.name, "]\n")
 
-	for _, line := range s.lines {
-		v += line.ToString()
+	for idx := range s.lines {
+		s.lines[idx].writeTo(w)
 	}
+}
 
-	return v
+// Writes the serialized section to [w], implements the `io.WriterTo` interface
+func (s *IniSection) WriteTo(w io.Writer) (int64, error) {
+	iw := newBufferedIniWriter(w)
+	s.writeTo(iw)
+	return iw.flush()
 }
 
-func (d *IniDoc) ToString() string {
-	var v string = ""
+func (s *IniSection) ToString() string {
+	var sb strings.Builder
+	s.writeTo(newIniWriter(&sb))
+	return sb.String()
+}
+
+func (d *IniDoc) writeTo(w *iniWriter) {
+	w.delimiter = d.opts.Delimiter
 
-	for _, line := range d.lines {
-		v += line.ToString()
+	for idx := range d.lines {
+		d.lines[idx].writeTo(w)
 	}
 
 	for _, section := range d.sections {
-		secStr := section.ToString()
-		if secStr != "" {
-			if len(v) >= 2 && v[len(v)-2:] != "\n\n" {
-				v += "\n"
-			}
-			v += secStr
+		if len(section.lines) == 0 {
+			continue
+		}
+		if w.n >= 2 && w.last != [2]byte{'\n', '\n'} {
+			w.writeString("\n")
 		}
+		section.writeTo(w)
 	}
+}
 
-	return v
+// Writes the serialized document to [w], implements the `io.WriterTo` interface
+func (d *IniDoc) WriteTo(w io.Writer) (int64, error) {
+	iw := newBufferedIniWriter(w)
+	d.writeTo(iw)
+	return iw.flush()
 }
 
+func (d *IniDoc) ToString() string {
+	var sb strings.Builder
+	d.writeTo(newIniWriter(&sb))
+	return sb.String()
+}
+
+// Returns a deep copy of the document, changes made to the copy do not affect the
+// original and vice versa
+func (d *IniDoc) Clone() *IniDoc {
+	c := &IniDoc{
+		lines:    slices.Clone(d.lines),
+		sections: make([]*IniSection, 0, len(d.sections)),
+		source:   d.source,
+		opts:     d.opts,
+	}
+
+	for _, section := range d.sections {
+		c.sections = append(c.sections, &IniSection{
+			root:    c,
+			name:    section.name,
+			lines:   slices.Clone(section.lines),
+			comment: section.comment,
+		})
+	}
+
+	return c
+}
+
+// Returns a deep copy of the section. Subsections of this section are copied as well,
+// those are accessible through the `Section` method of the copy.
+func (d *IniSection) Clone() *IniSection {
+	c := &IniSection{
+		name:    d.name,
+		lines:   slices.Clone(d.lines),
+		comment: d.comment,
+	}
+
+	if d.root == nil {
+		return c
+	}
+
+	c.root = &IniDoc{
+		lines:    []iniLine{},
+		sections: []*IniSection{},
+		source:   d.root.source,
+		opts:     d.root.opts,
+	}
+
+	for _, section := range d.root.sections {
+		if section == d {
+			c.root.sections = append(c.root.sections, c)
+		} else if d.name == "" || d.root.isSubsection(section.name, d.name) {
+			sub := section.Clone()
+			sub.root = c.root
+			c.root.sections = append(c.root.sections, sub)
+		}
+	}
+
+	return c
+}
+
+// Converts a copy of the document into a section, the document itself is not modified
 func docToSection(doc *IniDoc) *IniSection {
+	c := doc.Clone()
 	sec := IniSection{
-		root:  doc,
-		lines: doc.lines,
+		root:  c,
+		lines: c.lines,
 	}
-	doc.lines = []iniLine{}
+	c.lines = []iniLine{}
 	return &sec
 }
 
 func isCommentLine(l *iniLine) bool {
 	return l.lineType == lineTypeComment || l.lineType == lineTypeHashComment
 }
+
+func formatTime(value time.Time, layout string) string {
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	return value.Format(layout)
+}
+
+func parseTime(value string, layout string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	return time.Parse(layout, value)
+}
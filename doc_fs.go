@@ -1,25 +1,165 @@
 package ini
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"math/rand/v2"
 	"os"
+	"path/filepath"
+	"strconv"
 )
 
+type SaveOptions struct {
+	// Keep the previous version of the file next to it, with a `.bak` extension added
+	Backup bool
+	// Permissions of the file if it does not exist yet, defaults to 0644. The umask of
+	// the process is applied, like when creating the file with `os.WriteFile`.
+	// Permissions of existing files are always preserved.
+	Perm fs.FileMode
+}
+
+// Writes the document to the given file. The file is replaced atomically, see
+// `SaveWithOptions` for details.
 func (d *IniDoc) Save(filename string) error {
+	return d.SaveWithOptions(filename, SaveOptions{})
+}
+
+// Writes the document to the given file. The document is first written to a temporary
+// file in the same directory, synced to disk and then renamed over the target file,
+// so that the target is never left partially written. The mode and ownership of an
+// existing file are preserved.
+func (d *IniDoc) SaveWithOptions(filename string, opts SaveOptions) error {
 	if filename == "" {
 		return fmt.Errorf("invalid filepath: '%s'", filename)
 	}
 
-	result := d.ToString()
+	// write through symlinks instead of replacing them
+	if resolved, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = resolved
+	}
+
+	perm := opts.Perm
+	if perm == 0 {
+		perm = 0644
+	}
+
+	existing, err := os.Stat(filename)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if existing != nil {
+		perm = existing.Mode().Perm()
+	}
+
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
 
-	file, err := os.Create(filename)
+	tmp, err := createTemp(dir, "."+base+".tmp-", perm)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	tmpName := tmp.Name()
+
+	err = writeSynced(tmp, d, perm, existing)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if opts.Backup && existing != nil {
+		err = backupFile(filename, filename+".bak")
+		if err != nil {
+			os.Remove(tmpName)
+			return err
+		}
+	}
+
+	err = os.Rename(tmpName, filename)
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+func writeSynced(file *os.File, d *IniDoc, perm fs.FileMode, existing fs.FileInfo) error {
+	_, err := d.WriteTo(file)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		// the umask was applied when the file was created, the mode of the
+		// existing file is preserved exactly
+		err = file.Chmod(perm)
+		if err != nil {
+			return err
+		}
+
+		err = chownAs(file, existing)
+		if err != nil {
+			return err
+		}
+	}
+
+	return file.Sync()
+}
+
+// Creates a new file in [dir] with a random suffix added to the [prefix]. Unlike
+// `os.CreateTemp`, the file is created with the given permissions, to which the
+// umask of the process is applied.
+func createTemp(dir, prefix string, perm fs.FileMode) (*os.File, error) {
+	for try := 0; ; try++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if errors.Is(err, fs.ErrExist) && try < 10000 {
+			continue
+		}
+		return file, err
+	}
+}
 
-	_, err = file.WriteString(result)
+// Replaces the [backup] file with the current contents of [filename]
+func backupFile(filename, backup string) error {
+	err := os.Remove(backup)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// hard link is cheapest, since the original is replaced by a rename
+	// and not modified in place, fall back to a copy if it's not supported
+	if os.Link(filename, backup) == nil {
+		return nil
+	}
+
+	src, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
 
 	return err
 }
@@ -29,12 +169,67 @@ func Load(filename string) (*IniDoc, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
 
-	contents, err := io.ReadAll(file)
+	doc, err := NewDecoder(file).DecodeDoc()
 	if err != nil {
 		return nil, err
 	}
+	doc.source = filename
 
-	doc := Parse(string(contents))
 	return doc, nil
 }
+
+// Loads and parses the file with the given name from the [fsys] file system
+// (e.x. `embed.FS` or `os.DirFS`)
+func LoadFS(fsys fs.FS, name string) (*IniDoc, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	doc, err := NewDecoder(file).DecodeDoc()
+	if err != nil {
+		return nil, err
+	}
+	doc.source = name
+
+	return doc, nil
+}
+
+// Loads the given files and merges them into a single document, values from the later
+// files override the values from the earlier ones (see `Merge`). Files that do not
+// exist are skipped.
+func LoadLayered(paths ...string) (*IniDoc, error) {
+	docs := make([]*IniDoc, 0, len(paths))
+	for _, path := range paths {
+		doc, err := Load(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	return Merge(docs...), nil
+}
+
+// Same as `LoadLayered`, but the files are read from the [fsys] file system
+func LoadLayeredFS(fsys fs.FS, names ...string) (*IniDoc, error) {
+	docs := make([]*IniDoc, 0, len(names))
+	for _, name := range names {
+		doc, err := LoadFS(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	return Merge(docs...), nil
+}
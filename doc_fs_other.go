@@ -0,0 +1,16 @@
+//go:build !unix
+
+package ini
+
+import (
+	"io/fs"
+	"os"
+)
+
+func chownAs(file *os.File, like fs.FileInfo) error {
+	return nil
+}
+
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,45 @@
+//go:build unix
+
+package ini
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// Changes the owner of [file] to the owner of [like]. Lack of permissions to do so is
+// not considered an error, the file will then be owned by the current user.
+func chownAs(file *os.File, like fs.FileInfo) error {
+	stat, ok := like.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	if int(stat.Uid) == os.Getuid() && int(stat.Gid) == os.Getgid() {
+		return nil
+	}
+
+	err := file.Chown(int(stat.Uid), int(stat.Gid))
+	if errors.Is(err, fs.ErrPermission) {
+		return nil
+	}
+	return err
+}
+
+// Makes sure the rename of a file in [dir] is persisted
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	err = d.Sync()
+	if errors.Is(err, syscall.EINVAL) {
+		// some filesystems do not support syncing directories
+		return nil
+	}
+	return err
+}
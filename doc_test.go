@@ -1,7 +1,10 @@
 package ini_test
 
 import (
+	"bytes"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/ncpa0cpl/ini"
 )
@@ -161,3 +164,284 @@ k=v
 	expect(doc.SectionNames(true)).ToContain("A", "A.B", "A.B.C")
 	expect(doc.Section("A").Section("B").Section("C").Get("k")).ToBe("v")
 }
+
+func TestDocWriteTo(t *testing.T) {
+	expect := expect(t)
+
+	doc := ini.NewDoc()
+	doc.AddHashComment("generated")
+	doc.Set("top", "value")
+	doc.Section("s1").Set("k", "v")
+	doc.Section("s1").SetFieldComment("k", "field comment")
+	doc.Section("s1.sub").SetInt("n", 1)
+
+	var buf bytes.Buffer
+	n, err := doc.WriteTo(&buf)
+	expect(err).NoErr()
+	expect(n).ToBe(int64(buf.Len()))
+	expect(buf.String()).ToBe(doc.ToString())
+	expect(buf.String()).ToBe(`# generated
+top=value
+
+[s1]
+k=v ; field comment
+
+[s1.sub]
+n=1
+`)
+
+	writeErr := errors.New("write failed")
+	_, err = doc.WriteTo(failingWriter{writeErr})
+	expect(err).ToBe(writeErr)
+}
+
+func TestEncoder(t *testing.T) {
+	expect := expect(t)
+
+	var buf bytes.Buffer
+	err := ini.NewEncoder(&buf).Encode(&TestConfig{
+		K:  "v",
+		K1: 1,
+		User: User{
+			Name: "Tom",
+			Age:  2,
+		},
+	})
+	expect(err).NoErr()
+
+	expect(buf.String()).ToBe(`k=v
+k1=1
+k2=0
+k3=0
+
+[user]
+name=Tom
+age=2
+`)
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+func TestDocClone(t *testing.T) {
+	expect := expect(t)
+
+	docStr := `; base config
+name=base
+
+; server section
+[server]
+host=localhost ; local only
+
+[server.tls]
+cert=/etc/cert.pem
+`
+	base := ini.Parse(docStr)
+
+	variant := base.Clone()
+	variant.Set("name", "variant")
+	variant.Section("server").Set("host", "example.com")
+	variant.Section("server").SetSectionComment("changed")
+	variant.Section("server.tls").Del("cert")
+	variant.Section("extra").Set("k", "v")
+
+	expect(base.ToString()).ToBe(docStr)
+	expect(variant.ToString()).ToBe(`; base config
+name=variant
+
+; changed
+[server]
+host=example.com ; local only
+
+[extra]
+k=v
+`)
+
+	server := base.Section("server").Clone()
+	server.Set("port", "80")
+	server.Section("tls").Set("key", "/etc/key.pem")
+
+	expect(base.Section("server").Keys()).ToBe([]string{"host"})
+	expect(base.Section("server.tls").Keys()).ToBe([]string{"cert"})
+	expect(server.Keys()).ToBe([]string{"host", "port"})
+	expect(server.GetSectionComment()).ToBe("server section")
+	expect(server.Section("tls").Keys()).ToBe([]string{"cert", "key"})
+}
+
+type customDocMarshaler struct{}
+
+func (customDocMarshaler) MarshalINI() (ini.DocOrSection, error) {
+	return sharedDoc, nil
+}
+
+var sharedDoc = func() *ini.IniDoc {
+	doc := ini.NewDoc()
+	doc.Set("k", "v")
+	doc.Section("sub").Set("k2", "v2")
+	return doc
+}()
+
+func TestMarshalDoesNotModifyCustomDoc(t *testing.T) {
+	expect := expect(t)
+
+	type Ini struct {
+		First  customDocMarshaler
+		Second customDocMarshaler
+	}
+
+	str, err := ini.Marshal(Ini{})
+	expect(err).NoErr()
+	expect(str).ToBe(`[First]
+k=v
+
+[First.sub]
+k2=v2
+
+[Second]
+k=v
+
+[Second.sub]
+k2=v2
+`)
+	expect(sharedDoc.ToString()).ToBe("k=v\n\n[sub]\nk2=v2\n")
+}
+
+type customSectionMarshaler struct{}
+
+func (customSectionMarshaler) MarshalINI() (ini.DocOrSection, error) {
+	return sharedSection, nil
+}
+
+var sharedSection = func() *ini.IniSection {
+	section := ini.NewSection()
+	section.Set("k", "v")
+	section.Section("sub").Set("k2", "v2")
+	return section
+}()
+
+func TestMarshalDoesNotModifyCustomSection(t *testing.T) {
+	expect := expect(t)
+
+	type Ini struct {
+		First  customSectionMarshaler
+		Second customSectionMarshaler
+	}
+
+	str, err := ini.Marshal(Ini{})
+	expect(err).NoErr()
+	expect(str).ToBe(`[First]
+k=v
+
+[First.sub]
+k2=v2
+
+[Second]
+k=v
+
+[Second.sub]
+k2=v2
+`)
+	expect(sharedSection.Keys()).ToBe([]string{"k"})
+	expect(sharedSection.Section("sub").Keys()).ToBe([]string{"k2"})
+	expect(sharedSection.Section("First.sub").Keys()).ToBe([]string{})
+}
+
+func TestDocTimeValues(t *testing.T) {
+	expect := expect(t)
+
+	date := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	doc := ini.NewDoc()
+	doc.SetDuration("timeout", 90*time.Second)
+	doc.SetTime("created", date, "")
+	doc.Section("backup").SetTime("day", date, time.DateOnly)
+
+	expect(doc.ToString()).ToBe(`timeout=1m30s
+created=2024-03-01T12:30:00Z
+
+[backup]
+day=2024-03-01
+`)
+
+	timeout, err := doc.GetDuration("timeout")
+	expect(err).NoErr()
+	expect(timeout).ToBe(90 * time.Second)
+
+	created, err := doc.GetTime("created", "")
+	expect(err).NoErr()
+	expect(created.Equal(date)).ToBe(true)
+
+	day, err := doc.Section("backup").GetTime("day", time.DateOnly)
+	expect(err).NoErr()
+	expect(day).ToBe(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	missing, err := doc.GetTime("missing", "")
+	expect(err).NoErr()
+	expect(missing.IsZero()).ToBe(true)
+
+	doc.Set("timeout", "soon")
+	_, err = doc.GetDuration("timeout")
+	expect(err != nil).ToBe(true)
+
+	// available through the DocOrSection interface as well
+	var section ini.DocOrSection = ini.NewSection()
+	section.SetDuration("retry", 5*time.Second)
+	section.SetTime("day", date, time.DateOnly)
+	retry, err := section.GetDuration("retry")
+	expect(err).NoErr()
+	expect(retry).ToBe(5 * time.Second)
+	day, err = section.GetTime("day", time.DateOnly)
+	expect(err).NoErr()
+	expect(day).ToBe(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func TestDocCaseInsensitive(t *testing.T) {
+	expect := expect(t)
+
+	doc, err := ini.ParseWithOptions(`Name=first
+[Database]
+Host=localhost
+
+[database]
+PORT=5432
+
+[Database.Replica]
+host=replica
+`, ini.ParseOptions{DocOptions: ini.DocOptions{CaseInsensitiveKeys: true, CaseInsensitiveSections: true}})
+	expect(err).NoErr()
+
+	expect(doc.Get("name")).ToBe("first")
+	expect(doc.HasSection("DATABASE")).ToBe(true)
+	expect(doc.Section("database").Get("host")).ToBe("localhost")
+	expect(doc.Section("database").Get("port")).ToBe("5432")
+	expect(doc.Section("DATABASE").SubsectionNames()).ToBe([]string{"Replica"})
+	expect(doc.Section("database.replica").Get("HOST")).ToBe("replica")
+
+	doc.Set("NAME", "second")
+	doc.Section("DataBase").Set("host", "example.com")
+
+	// original spelling is kept
+	expect(doc.ToString()).ToBe(`Name=second
+
+[Database]
+Host=example.com
+
+PORT=5432
+
+[Database.Replica]
+host=replica
+`)
+
+	// only keys are case insensitive
+	doc = ini.NewDocWithOptions(ini.DocOptions{CaseInsensitiveKeys: true})
+	doc.Section("A").Set("k", "1")
+	doc.Section("a").Set("K", "2")
+	expect(doc.SectionNames()).ToBe([]string{"A", "a"})
+	expect(doc.Section("A").Get("K")).ToBe("1")
+	expect(doc.Clone().Options()).ToBe(ini.DocOptions{CaseInsensitiveKeys: true})
+}
@@ -0,0 +1,107 @@
+package ini
+
+import (
+	"bufio"
+	"io"
+)
+
+// Encoder writes ini documents to an output stream
+type Encoder struct {
+	w io.Writer
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w: w,
+	}
+}
+
+// Marshals [v] and writes the resulting document to the output stream
+func (enc *Encoder) Encode(v any) error {
+	doc, err := MarshalDoc(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = doc.WriteTo(enc.w)
+	return err
+}
+
+// Writes the serialized document to the output stream
+func (enc *Encoder) EncodeDoc(doc *IniDoc) error {
+	_, err := doc.WriteTo(enc.w)
+	return err
+}
+
+// Counts the bytes written to the underlying writer
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// Writer used during serialization, it keeps track of the last written bytes and
+// holds on to the first write error so that the serializers don't have to check it
+// after every write.
+type iniWriter struct {
+	w       io.StringWriter
+	n       int64
+	last    [2]byte
+	err     error
+	buf     *bufio.Writer
+	counter *countingWriter
+	// written between keys and values, `=` if empty
+	delimiter string
+}
+
+func newIniWriter(w io.StringWriter) *iniWriter {
+	return &iniWriter{
+		w: w,
+	}
+}
+
+func newBufferedIniWriter(w io.Writer) *iniWriter {
+	counter := &countingWriter{w: w}
+	buf := bufio.NewWriter(counter)
+
+	return &iniWriter{
+		w:       buf,
+		buf:     buf,
+		counter: counter,
+	}
+}
+
+func (w *iniWriter) writeString(strs ...string) {
+	for _, s := range strs {
+		if w.err != nil || s == "" {
+			continue
+		}
+
+		_, w.err = w.w.WriteString(s)
+		w.n += int64(len(s))
+
+		if len(s) >= 2 {
+			w.last = [2]byte{s[len(s)-2], s[len(s)-1]}
+		} else {
+			w.last = [2]byte{w.last[1], s[0]}
+		}
+	}
+}
+
+// Flushes any buffered data and returns the number of bytes written to the
+// underlying writer along with the first error that occurred
+func (w *iniWriter) flush() (int64, error) {
+	if w.buf == nil {
+		return w.n, w.err
+	}
+
+	if w.err == nil {
+		w.err = w.buf.Flush()
+	}
+	return w.counter.n, w.err
+}
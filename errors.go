@@ -0,0 +1,175 @@
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type ParseErrorReason int
+
+const (
+	// Section header is missing the closing bracket (e.x. `[section`)
+	ParseErrUnterminatedSection ParseErrorReason = iota + 1
+	// Section header does not contain a name (e.x. `[]`)
+	ParseErrInvalidSection
+	// Line contains a key but no `=` delimiter
+	ParseErrMissingDelimiter
+	// Key is empty or contains disallowed characters
+	ParseErrInvalidKey
+	// Escape character at the very end of the document
+	ParseErrDanglingEscape
+	// Key repeated within the same section, reported with the `DuplicateKeysError` policy
+	ParseErrDuplicateKey
+)
+
+func (r ParseErrorReason) String() string {
+	switch r {
+	case ParseErrUnterminatedSection:
+		return "unterminated section header"
+	case ParseErrInvalidSection:
+		return "invalid section name"
+	case ParseErrMissingDelimiter:
+		return "missing key-value delimiter"
+	case ParseErrInvalidKey:
+		return "invalid key"
+	case ParseErrDanglingEscape:
+		return "dangling escape character"
+	case ParseErrDuplicateKey:
+		return "duplicate key"
+	}
+	return fmt.Sprintf("unknown reason (%d)", int(r))
+}
+
+// Describes a single problem found in the parsed document
+type ParseError struct {
+	// Line number, starting at 1
+	Line int
+	// Column (in characters) of the start of the offending text, starting at 1
+	Column int
+	// Text that caused the error
+	Text   string
+	Reason ParseErrorReason
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s: %q", e.Line, e.Column, e.Reason, e.Text)
+}
+
+// List of all problems found in a parsed document
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d parse errors:\n%s", len(e), strings.Join(msgs, "\n"))
+}
+
+func (e ParseErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Describes a value that could not be unmarshaled into the type of a struct field
+type UnmarshalTypeError struct {
+	// Full path of the section containing the key, empty for top level keys
+	Section string
+	Key     string
+	// Raw value of the key
+	Value string
+	// Type the value was unmarshaled into
+	Type reflect.Type
+	// Path of the struct field, e.x. `Config.Servers[0].Port`
+	Field string
+	Err   error
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	var sb strings.Builder
+	if e.Section != "" {
+		sb.WriteString("[" + e.Section + "] ")
+	}
+	fmt.Fprintf(&sb, "%s=%q: expected %s", e.Key, e.Value, e.Type)
+
+	var numErr *strconv.NumError
+	if errors.As(e.Err, &numErr) {
+		if errors.Is(numErr.Err, strconv.ErrRange) {
+			sb.WriteString(": value out of range")
+		}
+	} else if e.Err != nil {
+		sb.WriteString(": " + e.Err.Error())
+	}
+
+	return sb.String()
+}
+
+func (e *UnmarshalTypeError) Unwrap() error {
+	return e.Err
+}
+
+// List of all errors found when unmarshaling with the `CollectErrors` or `DisallowUnknown` option
+type UnmarshalErrors []error
+
+func (e UnmarshalErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d unmarshal errors:\n%s", len(e), strings.Join(msgs, "\n"))
+}
+
+func (e UnmarshalErrors) Unwrap() []error {
+	return e
+}
+
+// Describes a key or a section of the document that does not match any struct field,
+// reported when unmarshaling with the `DisallowUnknown` option
+type UnknownKeyError struct {
+	// Full path of the section, empty for top level keys
+	Section string
+	// Empty if the whole section is unknown
+	Key string
+}
+
+func (e *UnknownKeyError) Error() string {
+	switch {
+	case e.Key == "":
+		return fmt.Sprintf("unknown section [%s]", e.Section)
+	case e.Section == "":
+		return fmt.Sprintf("unknown key '%s'", e.Key)
+	}
+	return fmt.Sprintf("unknown key '%s' in section [%s]", e.Key, e.Section)
+}
+
+// Returned when a key or section marked with the `required` tag option is missing
+type RequiredError struct {
+	// Full path of the section, for a missing section it's the path of that section
+	Section string
+	// Empty if the whole section is missing
+	Key string
+}
+
+func (e *RequiredError) Error() string {
+	switch {
+	case e.Key == "":
+		return fmt.Sprintf("required section [%s] is missing", e.Section)
+	case e.Section == "":
+		return fmt.Sprintf("required key '%s' is missing", e.Key)
+	}
+	return fmt.Sprintf("required key '%s' in section [%s] is missing", e.Key, e.Section)
+}
@@ -0,0 +1,136 @@
+package ini
+
+import (
+	"reflect"
+)
+
+// Struct field that is marshaled and unmarshaled, possibly promoted from an embedded struct
+type structField struct {
+	field reflect.StructField
+	info  *fieldInfo
+	// index sequence for `reflect.Value.FieldByIndex`
+	index []int
+}
+
+// Returns the fields of the [t] struct type that are marshaled and unmarshaled, in the
+// order of declaration.
+//
+// Embedded structs without an alias in the tag, as well as struct fields with the `inline`
+// option, are flattened into the parent section, unless the `section` option is given.
+// Name conflicts follow the `encoding/json` rules: the least nested field wins, if there
+// are several of those, the one with an alias in the tag wins, otherwise all of them
+// are ignored.
+func structFields(t reflect.Type) []structField {
+	fields := make([]structField, 0, t.NumField())
+	collectFields(t, nil, map[reflect.Type]bool{}, &fields)
+
+	byAlias := make(map[string][]int, len(fields))
+	for idx, f := range fields {
+		byAlias[f.info.Alias] = append(byAlias[f.info.Alias], idx)
+	}
+
+	result := make([]structField, 0, len(fields))
+	for idx, f := range fields {
+		if dominantField(fields, byAlias[f.info.Alias]) == idx {
+			result = append(result, f)
+		}
+	}
+
+	return result
+}
+
+func collectFields(t reflect.Type, index []int, visited map[reflect.Type]bool, fields *[]structField) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("ini") == "-" {
+			continue
+		}
+
+		info := parseFieldTag("ini", f)
+		fieldIndex := append(index[:len(index):len(index)], i)
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		isStruct := ft.Kind() == reflect.Struct && !isValueType(f.Type)
+		embedded := f.Anonymous && !info.Tagged && !info.Section
+		if isStruct && (embedded || info.Inline) {
+			if !f.IsExported() && f.Type.Kind() == reflect.Ptr {
+				// pointers to unexported types can't be allocated
+				continue
+			}
+			collectFields(ft, fieldIndex, visited, fields)
+			continue
+		}
+
+		if info.Skip {
+			continue
+		}
+
+		*fields = append(*fields, structField{
+			field: f,
+			info:  info,
+			index: fieldIndex,
+		})
+	}
+}
+
+// Returns the index of the field that is used among the given conflicting fields,
+// or -1 if none of them is
+func dominantField(fields []structField, candidates []int) int {
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+
+	depth := len(fields[candidates[0]].index)
+	for _, idx := range candidates[1:] {
+		depth = min(depth, len(fields[idx].index))
+	}
+
+	dominant := -1
+	count, taggedCount := 0, 0
+	for _, idx := range candidates {
+		f := fields[idx]
+		if len(f.index) != depth {
+			continue
+		}
+		count++
+		if f.info.Tagged {
+			taggedCount++
+			dominant = idx
+		} else if taggedCount == 0 {
+			dominant = idx
+		}
+	}
+
+	if count == 1 || taggedCount == 1 {
+		return dominant
+	}
+	return -1
+}
+
+// Returns the nested field of [v] at the given index sequence. Nil pointers to embedded
+// structs are allocated if [alloc] is true, otherwise false is returned.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
@@ -1,8 +1,12 @@
 package ini_test
 
 import (
+	"errors"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"testing"
+	"testing/fstest"
 
 	"github.com/ncpa0cpl/ini"
 )
@@ -581,3 +585,295 @@ k=v
 k=v
 `)
 }
+
+func TestParseStrict(t *testing.T) {
+	expect := expect(t)
+
+	docStr := `a=b
+[section
+k=v
+keyonly
+[valid]
+=novalue
+ok=1
+last=trailing\`
+
+	doc, err := ini.ParseStrict(docStr)
+
+	var parseErrs ini.ParseErrors
+	expect(errors.As(err, &parseErrs)).ToBe(true)
+	expect(len(parseErrs)).ToBe(4)
+
+	expect(*parseErrs[0]).ToBe(ini.ParseError{Line: 2, Column: 1, Text: "[section", Reason: ini.ParseErrUnterminatedSection})
+	expect(*parseErrs[1]).ToBe(ini.ParseError{Line: 4, Column: 1, Text: "keyonly", Reason: ini.ParseErrMissingDelimiter})
+	expect(*parseErrs[2]).ToBe(ini.ParseError{Line: 6, Column: 1, Text: "", Reason: ini.ParseErrInvalidKey})
+	expect(*parseErrs[3]).ToBe(ini.ParseError{Line: 8, Column: 1, Text: "\\", Reason: ini.ParseErrDanglingEscape})
+
+	var firstErr *ini.ParseError
+	expect(errors.As(err, &firstErr)).ToBe(true)
+	expect(firstErr.Error()).ToBe(`line 2, column 1: unterminated section header: "[section"`)
+
+	// everything that could be parsed is still available
+	expect(doc.Get("a")).ToBe("b")
+	expect(doc.Get("k")).ToBe("v")
+	expect(doc.Section("valid").Get("ok")).ToBe("1")
+	expect(doc.Section("valid").Get("last")).ToBe("trailing")
+
+	_, err = ini.ParseStrict("a=b\n\n[s]\n  k = v ; comment\n")
+	expect(err).NoErr()
+}
+
+func TestIniSaveAtomic(t *testing.T) {
+	expect := expect(t)
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.ini")
+
+	expect(os.WriteFile(filename, []byte("old=1\n"), 0600)).NoErr()
+
+	doc := ini.NewDoc()
+	doc.SetInt("new", 2)
+	expect(doc.SaveWithOptions(filename, ini.SaveOptions{Backup: true})).NoErr()
+
+	bts, err := os.ReadFile(filename)
+	expect(err).NoErr()
+	expect(string(bts)).ToBe("new=2\n")
+
+	info, err := os.Stat(filename)
+	expect(err).NoErr()
+	expect(info.Mode().Perm()).ToBe(os.FileMode(0600))
+
+	bak, err := os.ReadFile(filename + ".bak")
+	expect(err).NoErr()
+	expect(string(bak)).ToBe("old=1\n")
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	expect(err).NoErr()
+	expect(len(entries)).ToBe(2)
+
+	// new files get the same permissions as files created by os.WriteFile (umask applied)
+	refFile := filepath.Join(dir, "ref.ini")
+	expect(os.WriteFile(refFile, []byte{}, 0666)).NoErr()
+	refInfo, err := os.Stat(refFile)
+	expect(err).NoErr()
+
+	newFile := filepath.Join(dir, "new.ini")
+	expect(doc.SaveWithOptions(newFile, ini.SaveOptions{Perm: 0666})).NoErr()
+
+	info, err = os.Stat(newFile)
+	expect(err).NoErr()
+	expect(info.Mode().Perm()).ToBe(refInfo.Mode().Perm())
+
+	// the mode of existing files is preserved regardless of the umask
+	expect(os.Chmod(newFile, 0666)).NoErr()
+	expect(doc.Save(newFile)).NoErr()
+	info, err = os.Stat(newFile)
+	expect(err).NoErr()
+	expect(info.Mode().Perm()).ToBe(os.FileMode(0666))
+}
+
+func TestIniLoadFS(t *testing.T) {
+	expect := expect(t)
+
+	fsys := fstest.MapFS{
+		"conf/app.ini": &fstest.MapFile{Data: []byte("name=app\n\n[db]\nport=5432\n")},
+	}
+
+	doc, err := ini.LoadFS(fsys, "conf/app.ini")
+	expect(err).NoErr()
+	expect(doc.Get("name")).ToBe("app")
+	expect(doc.Section("db").Get("port")).ToBe("5432")
+
+	_, err = ini.LoadFS(fsys, "missing.ini")
+	expect(errors.Is(err, fs.ErrNotExist)).ToBe(true)
+
+	doc, err = ini.LoadFS(os.DirFS("."), "test.ini")
+	expect(err).NoErr()
+	expect(doc.Section("s2").Get("k2")).ToBe("945")
+}
+
+func TestParseDuplicateKeys(t *testing.T) {
+	expect := expect(t)
+
+	content := `host=a ; first
+host=b ; second
+tag[]=x
+tag[]=y
+
+[section]
+port=1
+port=2
+`
+
+	doc := ini.Parse(content)
+	expect(doc.Get("host")).ToBe("b")
+	expect(doc.GetComment("host")).ToBe("second")
+	expect(doc.GetAll("tag[]")).ToBe([]string{"x", "y"})
+
+	doc, err := ini.ParseWithOptions(content, ini.ParseOptions{DuplicateKeys: ini.DuplicateKeysFirstWins})
+	expect(err).NoErr()
+	expect(doc.Get("host")).ToBe("a")
+	expect(doc.GetComment("host")).ToBe("first")
+	expect(doc.Section("section").GetAll("port")).ToBe([]string{"1"})
+
+	doc, err = ini.ParseWithOptions(content, ini.ParseOptions{DuplicateKeys: ini.DuplicateKeysKeepAll})
+	expect(err).NoErr()
+	expect(doc.Get("host")).ToBe("a")
+	expect(doc.GetAll("host")).ToBe([]string{"a", "b"})
+	expect(doc.Section("section").GetAll("port")).ToBe([]string{"1", "2"})
+	expect(doc.ToString()).ToBe(content)
+
+	doc, err = ini.ParseWithOptions(content+"broken\n", ini.ParseOptions{DuplicateKeys: ini.DuplicateKeysError})
+	expect(doc.Get("host")).ToBe("a")
+
+	var parseErrs ini.ParseErrors
+	expect(errors.As(err, &parseErrs)).ToBe(true)
+	// malformed lines are reported only in strict mode
+	expect(len(parseErrs)).ToBe(2)
+	expect(parseErrs[0].Error()).ToBe(`line 2, column 1: duplicate key: "host"`)
+	expect(parseErrs[1].Line).ToBe(8)
+	expect(parseErrs[1].Reason).ToBe(ini.ParseErrDuplicateKey)
+}
+
+func TestDocAddValue(t *testing.T) {
+	expect := expect(t)
+
+	doc := ini.NewDoc()
+	doc.AddValue("host", "a")
+	doc.AddValue("host", "b")
+	doc.Section("s").AddValue("k", "1")
+	doc.Section("s").AddValue("k", "2")
+
+	expect(doc.GetAll("host")).ToBe([]string{"a", "b"})
+	expect(doc.GetAll("missing")).ToBe([]string{})
+	expect(doc.ToString()).ToBe("host=a\nhost=b\n\n[s]\nk=1\nk=2\n")
+}
+
+func TestParseDuplicateSections(t *testing.T) {
+	expect := expect(t)
+
+	content := `[Interface]
+PrivateKey=abc
+
+; first peer
+[Peer]
+PublicKey=p1
+AllowedIPs=10.0.0.1/32
+
+[Peer]
+PublicKey=p2
+AllowedIPs=10.0.0.2/32
+`
+
+	doc := ini.Parse(content)
+	expect(len(doc.Sections("Peer"))).ToBe(1)
+	expect(doc.Section("Peer").Get("PublicKey")).ToBe("p2")
+
+	doc, err := ini.ParseWithOptions(content, ini.ParseOptions{DuplicateSections: ini.DuplicateSectionsSeparate})
+	expect(err).NoErr()
+
+	peers := doc.Sections("Peer")
+	expect(len(peers)).ToBe(2)
+	expect(peers[0].Get("PublicKey")).ToBe("p1")
+	expect(peers[0].GetSectionComment()).ToBe("first peer")
+	expect(peers[1].Get("PublicKey")).ToBe("p2")
+	expect(doc.Section("Peer")).ToBe(peers[0])
+	expect(doc.Sections("Missing")).ToBe([]*ini.IniSection{})
+	expect(doc.ToString()).ToBe(content)
+
+	peers[1].Set("AllowedIPs", "10.0.0.3/32")
+	expect(doc.Sections("Peer")[1].Get("AllowedIPs")).ToBe("10.0.0.3/32")
+	expect(doc.Sections("Peer")[0].Get("AllowedIPs")).ToBe("10.0.0.1/32")
+}
+
+func TestParseDelimiters(t *testing.T) {
+	expect := expect(t)
+
+	content := `name: app
+path = /usr/bin
+escaped\:key: value
+url: http://example.com
+
+[server]
+port:8080
+`
+
+	doc, err := ini.ParseWithOptions(content, ini.ParseOptions{Strict: true, Delimiters: "=:"})
+	expect(err).NoErr()
+	expect(doc.Get("name")).ToBe("app")
+	expect(doc.Get("path")).ToBe("/usr/bin")
+	expect(doc.Get("escaped:key")).ToBe("value")
+	expect(doc.Get("url")).ToBe("http://example.com")
+	expect(doc.Section("server").Get("port")).ToBe("8080")
+
+	// `=` is the only delimiter by default
+	doc = ini.Parse("a: b\nc=d: e\n")
+	expect(doc.Has("a")).ToBe(false)
+	expect(doc.Get("c")).ToBe("d: e")
+
+	doc, err = ini.ParseWithOptions(`host  example.com
+port	22
+user = root
+empty 
+missing
+`, ini.ParseOptions{Strict: true, Delimiters: " \t="})
+
+	var parseErrs ini.ParseErrors
+	expect(errors.As(err, &parseErrs)).ToBe(true)
+	expect(len(parseErrs)).ToBe(1)
+	expect(parseErrs[0].Line).ToBe(5)
+	expect(parseErrs[0].Reason).ToBe(ini.ParseErrMissingDelimiter)
+
+	expect(doc.Get("host")).ToBe("example.com")
+	expect(doc.Get("port")).ToBe("22")
+	expect(doc.Get("user")).ToBe("root")
+	expect(doc.Has("empty")).ToBe(true)
+	expect(doc.Get("empty")).ToBe("")
+}
+
+func TestSerializeDelimiter(t *testing.T) {
+	expect := expect(t)
+
+	content := `name: app
+
+[server]
+port: 8080
+`
+
+	doc, err := ini.ParseWithOptions(content, ini.ParseOptions{
+		Delimiters: ":",
+		DocOptions: ini.DocOptions{Delimiter: ": "},
+	})
+	expect(err).NoErr()
+	expect(doc.ToString()).ToBe(content)
+	expect(doc.Section("server").ToString()).ToBe("[server]\nport: 8080\n")
+	expect(doc.Clone().ToString()).ToBe(content)
+
+	doc = ini.NewDocWithOptions(ini.DocOptions{Delimiter: " = "})
+	doc.Set("k", "v")
+	expect(doc.ToString()).ToBe("k = v\n")
+
+	// delimiter characters in keys are escaped
+	doc = ini.NewDocWithOptions(ini.DocOptions{Delimiter: ": "})
+	doc.Set("a:b", "v")
+	doc.Set("c=d", "v")
+	expect(doc.ToString()).ToBe("a\\:b: v\nc=d: v\n")
+
+	parsed, err := ini.ParseWithOptions(doc.ToString(), ini.ParseOptions{Delimiters: ":"})
+	expect(err).NoErr()
+	expect(parsed.Keys()).ToBe([]string{"a:b", "c=d"})
+
+	doc = ini.NewDoc()
+	doc.Set("a=b", "v")
+	expect(doc.ToString()).ToBe("a\\=b=v\n")
+	expect(ini.Parse(doc.ToString()).Get("a=b")).ToBe("v")
+
+	doc = ini.NewDocWithOptions(ini.DocOptions{Delimiter: " "})
+	doc.Set("a b", "v")
+	expect(doc.ToString()).ToBe("a\\ b v\n")
+
+	parsed, err = ini.ParseWithOptions(doc.ToString(), ini.ParseOptions{Delimiters: " "})
+	expect(err).NoErr()
+	expect(parsed.Get("a b")).ToBe("v")
+}
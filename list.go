@@ -0,0 +1,96 @@
+package ini
+
+import "strings"
+
+// Joins the values into a comma separated list. Values containing commas, quotes or
+// leading or trailing whitespace are quoted, with quotes inside of them doubled
+// (e.x. `a,"b,c","d ""e"""`).
+func joinList(values []string) string {
+	var sb strings.Builder
+
+	for idx, value := range values {
+		if idx > 0 {
+			sb.WriteByte(',')
+		}
+
+		if needsQuoting(value) {
+			sb.WriteByte('"')
+			sb.WriteString(strings.ReplaceAll(value, `"`, `""`))
+			sb.WriteByte('"')
+		} else {
+			sb.WriteString(value)
+		}
+	}
+
+	return sb.String()
+}
+
+func needsQuoting(value string) bool {
+	if value == "" {
+		return false
+	}
+	if strings.ContainsAny(value, `,"`) {
+		return true
+	}
+	return value[0] == ' ' || value[len(value)-1] == ' '
+}
+
+// Splits a comma separated list created by `joinList`. Whitespace around the unquoted
+// values is ignored, empty string results in an empty list.
+func splitList(list string) []string {
+	if strings.TrimSpace(list) == "" {
+		return []string{}
+	}
+
+	values := make([]string, 0, strings.Count(list, ",")+1)
+	value := make([]rune, 0, 16)
+	quoted := false
+	inQuotes := false
+
+	runes := []rune(list)
+	for idx := 0; idx < len(runes); idx++ {
+		char := runes[idx]
+
+		if inQuotes {
+			if char == '"' {
+				if idx+1 < len(runes) && runes[idx+1] == '"' {
+					value = append(value, '"')
+					idx++
+				} else {
+					inQuotes = false
+				}
+			} else {
+				value = append(value, char)
+			}
+			continue
+		}
+
+		switch char {
+		case ',':
+			values = append(values, finishListValue(value, quoted))
+			value = value[:0]
+			quoted = false
+		case '"':
+			if strings.TrimSpace(string(value)) == "" {
+				value = value[:0]
+				quoted = true
+				inQuotes = true
+			} else {
+				value = append(value, char)
+			}
+		default:
+			if !quoted {
+				value = append(value, char)
+			}
+		}
+	}
+
+	return append(values, finishListValue(value, quoted))
+}
+
+func finishListValue(value []rune, quoted bool) string {
+	if quoted {
+		return string(value)
+	}
+	return strings.TrimSpace(string(value))
+}
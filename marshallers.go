@@ -1,24 +1,32 @@
 package ini
 
 import (
+	"cmp"
+	"encoding"
 	"fmt"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type DocOrSection interface {
 	Del(key string)
 	Get(key string) string
 	GetBool(key string) (bool, error)
+	GetDuration(key string) (time.Duration, error)
 	GetFloat(key string) (float64, error)
 	GetInt(key string) (int64, error)
+	GetTime(key string, layout string) (time.Time, error)
 	GetUint(key string) (uint64, error)
 	Set(key string, value string)
 	SetBool(key string, value bool)
+	SetDuration(key string, value time.Duration)
 	SetFieldComment(fieldKey string, value string)
 	SetFloat(key string, value float64)
 	SetInt(key string, value int64)
+	SetTime(key string, value time.Time, layout string)
 	SetUint(key string, value uint64)
 	AddComment(value string)
 	AddHashComment(value string)
@@ -35,166 +43,721 @@ type Unmarshalable interface {
 	UnmarshalINI(DocOrSection) error
 }
 
-func unmarshalField(strct reflect.Value, field reflect.StructField, finfo *fieldInfo, doc DocOrSection) error {
-	kind := field.Type.Kind()
+// Implemented by types that are stored as the value of a single key, takes precedence
+// over `encoding.TextMarshaler`
+type ValueMarshalable interface {
+	MarshalINIValue() (string, error)
+}
 
-	switch kind {
-	case reflect.Bool:
-		strvalue := doc.Get(finfo.Alias)
-		if strvalue == "true" {
-			strct.FieldByName(finfo.Name).SetBool(true)
+// Implemented by types that are parsed from the value of a single key, takes precedence
+// over `encoding.TextUnmarshaler`
+type ValueUnmarshalable interface {
+	UnmarshalINIValue(value string) error
+}
+
+type UnmarshalOptions struct {
+	// When enabled, unmarshaling does not stop at the first error, all errors are returned
+	// as `UnmarshalErrors` instead. Fields that could not be unmarshaled are left untouched.
+	CollectErrors bool
+	// When enabled, every key and section of the document that is not unmarshaled into
+	// any struct field is reported as an `*UnknownKeyError`
+	DisallowUnknown bool
+	// Options used to parse the data given to `UnmarshalWithOptions`, e.x. the
+	// `DuplicateKeysKeepAll` policy needed by lists using the `repeat` format.
+	// Documents given to `UnmarshalDocWithOptions` are already parsed.
+	Parse ParseOptions
+}
+
+// Holds the state of a single unmarshal call
+type unmarshaler struct {
+	opts UnmarshalOptions
+	errs UnmarshalErrors
+	// path of the struct field being unmarshaled (e.x. `Config`, `Servers`, `[0]`, `Port`)
+	path []string
+
+	// keys consumed by the struct fields, by section path, an empty key marks the
+	// section itself as consumed
+	used map[string]map[string]bool
+	// sections consumed as a whole, together with their subsections (custom unmarshalers)
+	usedAll []string
+}
+
+// Returns the full path of the section, empty for the document
+func sectionPath(doc docOrSection) string {
+	if s, ok := doc.(*IniSection); ok {
+		return s.name
+	}
+	return ""
+}
+
+// Returns the document the [doc] belongs to, nil for sections without a document
+func docRoot(doc docOrSection) *IniDoc {
+	switch v := doc.(type) {
+	case *IniDoc:
+		return v
+	case *IniSection:
+		return v.root
+	}
+	return nil
+}
+
+// Marks the [key] of [doc] as consumed, an empty key marks the section itself
+func (u *unmarshaler) use(doc docOrSection, key string) {
+	if !u.opts.DisallowUnknown {
+		return
+	}
+	if u.used == nil {
+		u.used = make(map[string]map[string]bool)
+	}
+	root := docRoot(doc)
+	path := root.sectionID(sectionPath(doc))
+	if u.used[path] == nil {
+		u.used[path] = make(map[string]bool)
+	}
+	u.used[path][root.keyID(key)] = true
+}
+
+// Marks the [section] with all of it's keys and subsections as consumed
+func (u *unmarshaler) useAll(section *IniSection) {
+	if u.opts.DisallowUnknown {
+		u.usedAll = append(u.usedAll, section.name)
+	}
+}
+
+func (u *unmarshaler) isUsed(doc *IniDoc, section string, key string) bool {
+	for _, name := range u.usedAll {
+		if doc.sectionsEqual(section, name) || doc.isSubsection(section, name) {
+			return true
 		}
-	case reflect.String:
-		value := doc.Get(finfo.Alias)
-		strct.FieldByName(finfo.Name).SetString(value)
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		value, err := doc.GetInt(finfo.Alias)
-		if err != nil {
-			return err
+	}
+	return u.used[doc.sectionID(section)][doc.keyID(key)]
+}
+
+// Returns an error for every key and section of the [doc] that was not consumed
+func (u *unmarshaler) unknownKeys(doc *IniDoc) UnmarshalErrors {
+	var errs UnmarshalErrors
+
+	for _, line := range doc.lines {
+		if line.lineType == lineTypeKv && !u.isUsed(doc, "", line.key) {
+			errs = append(errs, &UnknownKeyError{Key: line.key})
 		}
-		strct.FieldByName(finfo.Name).SetInt(value)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		value, err := doc.GetUint(finfo.Alias)
-		if err != nil {
-			return err
+	}
+
+	reported := make(map[string]bool)
+	for _, section := range doc.sections {
+		if !u.isUsed(doc, section.name, "") {
+			// sections without keys that only hold subsections are not reported
+			isParent := len(section.lines) == 0 && slices.ContainsFunc(doc.sections, func(s *IniSection) bool {
+				return doc.isSubsection(s.name, section.name)
+			})
+			if !isParent && !reported[doc.sectionID(section.name)] {
+				reported[doc.sectionID(section.name)] = true
+				errs = append(errs, &UnknownKeyError{Section: section.name})
+			}
+			continue
 		}
-		strct.FieldByName(finfo.Name).SetUint(value)
-	case reflect.Float32, reflect.Float64:
-		value, err := doc.GetFloat(finfo.Alias)
-		if err != nil {
-			return err
+
+		for _, line := range section.lines {
+			if line.lineType == lineTypeKv && !u.isUsed(doc, section.name, line.key) {
+				errs = append(errs, &UnknownKeyError{Section: section.name, Key: line.key})
+			}
 		}
-		strct.FieldByName(finfo.Name).SetFloat(value)
-	case reflect.Struct, reflect.Ptr:
-		fieldVal := strct.FieldByName(finfo.Name)
+	}
 
-		sectElem := fieldVal
-		sectType := field.Type
-		sectKind := sectType.Kind()
+	return errs
+}
+
+// Reports the [err], returns nil if the unmarshaling should continue
+func (u *unmarshaler) fail(err error) error {
+	if err != nil && u.opts.CollectErrors {
+		u.errs = append(u.errs, err)
+		return nil
+	}
+	return err
+}
+
+func (u *unmarshaler) push(name string) {
+	u.path = append(u.path, name)
+}
 
-		if fieldVal.IsZero() && sectKind == reflect.Ptr {
-			fieldVal.Set(reflect.New(fieldVal.Type().Elem()))
+func (u *unmarshaler) pop() {
+	u.path = u.path[:len(u.path)-1]
+}
+
+// Returns the path of the current struct field, e.x. `Config.Servers[0].Port`
+func (u *unmarshaler) fieldPath() string {
+	var sb strings.Builder
+	for _, name := range u.path {
+		if sb.Len() > 0 && !strings.HasPrefix(name, "[") {
+			sb.WriteString(".")
 		}
+		sb.WriteString(name)
+	}
+	return sb.String()
+}
 
-		docSection := doc.Section(finfo.Alias)
+func (u *unmarshaler) typeError(doc docOrSection, key string, value string, t reflect.Type, err error) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
 
-		vUnmarshalable, ok := fieldVal.Interface().(Unmarshalable)
-		if ok {
-			err := vUnmarshalable.UnmarshalINI(docSection)
-			if err != nil {
-				return err
+	return u.fail(&UnmarshalTypeError{
+		Section: sectionPath(doc),
+		Key:     key,
+		Value:   value,
+		Type:    t,
+		Field:   u.fieldPath(),
+		Err:     err,
+	})
+}
+
+func (u *unmarshaler) unmarshalField(fieldVal reflect.Value, field reflect.StructField, finfo *fieldInfo, doc docOrSection) error {
+	if finfo.Skip {
+		return nil
+	}
+
+	kind := field.Type.Kind()
+
+	if finfo.Required {
+		isList := kind == reflect.Slice || kind == reflect.Array
+		switch {
+		case isValueType(field.Type):
+			if !doc.Has(finfo.Alias) {
+				return u.fail(&RequiredError{Section: sectionPath(doc), Key: finfo.Alias})
 			}
+		case isList && !isSectionType(field.Type.Elem()):
+			if !doc.Has(finfo.listKey()) {
+				return u.fail(&RequiredError{Section: sectionPath(doc), Key: finfo.listKey()})
+			}
+		default:
+			if !doc.HasSection(finfo.Alias) {
+				path := finfo.Alias
+				if parent := sectionPath(doc); parent != "" {
+					path = parent + "." + path
+				}
+				return u.fail(&RequiredError{Section: path})
+			}
+		}
+	}
+
+	if isValueType(field.Type) {
+		var value string
+		if doc.Has(finfo.Alias) {
+			u.use(doc, finfo.Alias)
+			value = doc.Get(finfo.Alias)
+		} else if finfo.HasDefault {
+			value = finfo.Default
+		} else {
+			// leave the preset value untouched
 			return nil
 		}
+		err := unmarshalValue(fieldVal, value, finfo.Layout)
+		if err != nil {
+			return u.typeError(doc, finfo.Alias, value, field.Type, err)
+		}
+		return nil
+	}
 
-		if sectKind == reflect.Ptr {
-			sectType = sectType.Elem()
-			sectKind = sectType.Kind()
-			sectElem = fieldVal.Elem()
+	switch kind {
+	case reflect.Slice, reflect.Array:
+		if isSectionType(field.Type.Elem()) {
+			return u.unmarshalSectionList(fieldVal, finfo, doc)
 		}
 
-		if sectKind != reflect.Struct {
+		if !isValueType(field.Type.Elem()) {
 			return nil
 		}
 
-		sectFields := reflect.VisibleFields(sectType)
-		for _, sectF := range sectFields {
-			sectFieldInfo := parseFieldTag("ini", sectF)
-			err := unmarshalField(sectElem, sectF, sectFieldInfo, docSection)
-			if err != nil {
-				return err
-			}
+		var values []string
+		switch {
+		case finfo.ListFormat == listFormatComma && doc.Has(finfo.Alias):
+			u.use(doc, finfo.Alias)
+			values = splitList(doc.Get(finfo.Alias))
+		case finfo.ListFormat != listFormatComma && doc.Has(finfo.listKey()):
+			u.use(doc, finfo.listKey())
+			values = doc.GetAll(finfo.listKey())
+		case finfo.HasDefault:
+			values = splitList(finfo.Default)
+		default:
+			// leave the preset value untouched
+			return nil
 		}
-	case reflect.Map:
-		fieldVal := strct.FieldByName(finfo.Name)
 
-		keyKind := fieldVal.Type().Key().Kind()
-		if keyKind != reflect.String {
+		return u.unmarshalList(fieldVal, values, finfo, doc)
+	case reflect.Struct, reflect.Ptr:
+		return u.unmarshalSection(fieldVal, doc.Section(finfo.Alias))
+	case reflect.Map:
+		keyType := fieldVal.Type().Key()
+		if keyType.Kind() != reflect.String {
 			return nil
 		}
 
 		docSection := doc.Section(finfo.Alias)
-		docKeys := docSection.Keys()
-
-		if fieldVal.IsZero() {
-			fieldVal.Set(reflect.MakeMapWithSize(
-				reflect.MapOf(
-					reflect.TypeOf(""),
-					field.Type.Elem(),
-				),
-				len(docKeys),
-			))
-		}
-
+		u.use(docSection, "")
 		mapElemType := field.Type.Elem()
-		switch mapElemType.Kind() {
-		case reflect.String:
-			for _, key := range docKeys {
-				value := docSection.Get(key)
-				fieldVal.SetMapIndex(
-					reflect.ValueOf(key),
-					reflect.ValueOf(value),
-				)
+
+		if isSectionType(mapElemType) {
+			// each subsection is a map element
+			names := docSection.SubsectionNames()
+			if fieldVal.IsNil() {
+				fieldVal.Set(reflect.MakeMapWithSize(fieldVal.Type(), len(names)))
 			}
-		case reflect.Bool:
-			for _, key := range docKeys {
-				value, err := docSection.GetBool(key)
+
+			for _, name := range names {
+				value := reflect.New(mapElemType).Elem()
+				u.push("[" + name + "]")
+				err := u.unmarshalSection(value, docSection.Section(name))
+				u.pop()
 				if err != nil {
 					return err
 				}
-				fieldVal.SetMapIndex(
-					reflect.ValueOf(key),
-					reflect.ValueOf(value),
-				)
+				fieldVal.SetMapIndex(reflect.ValueOf(name).Convert(keyType), value)
 			}
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			for _, key := range docKeys {
-				value, err := docSection.GetInt(key)
+			return nil
+		}
+
+		docKeys := docSection.Keys()
+
+		if fieldVal.IsNil() {
+			fieldVal.Set(reflect.MakeMapWithSize(fieldVal.Type(), len(docKeys)))
+		}
+
+		if !isValueType(mapElemType) && mapElemType.Kind() != reflect.Interface {
+			return nil
+		}
+
+		for _, key := range docKeys {
+			u.use(docSection, key)
+			value := reflect.New(mapElemType).Elem()
+			strValue := docSection.Get(key)
+			err := unmarshalValue(value, strValue, finfo.Layout)
+			if err != nil {
+				u.push("[" + key + "]")
+				err = u.typeError(docSection, key, strValue, mapElemType, err)
+				u.pop()
 				if err != nil {
 					return err
 				}
-				fieldVal.SetMapIndex(
-					reflect.ValueOf(key),
-					reflect.ValueOf(value).Convert(mapElemType),
-				)
+				continue
 			}
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			for _, key := range docKeys {
-				value, err := docSection.GetUint(key)
-				if err != nil {
-					return err
-				}
-				fieldVal.SetMapIndex(
-					reflect.ValueOf(key),
-					reflect.ValueOf(value).Convert(mapElemType),
-				)
+			fieldVal.SetMapIndex(reflect.ValueOf(key).Convert(keyType), value)
+		}
+	}
+
+	return nil
+}
+
+// Unmarshals all fields of the [v] struct from the given document or section,
+// nil pointers to embedded structs are allocated
+func (u *unmarshaler) unmarshalStruct(v reflect.Value, doc docOrSection) error {
+	for _, f := range structFields(v.Type()) {
+		fieldVal, _ := fieldByIndex(v, f.index, true)
+		u.push(f.field.Name)
+		err := u.unmarshalField(fieldVal, f.field, f.info, doc)
+		u.pop()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unmarshals the [section] into [v], which is either a struct, a pointer to a struct
+// or an `Unmarshalable`. Nil pointers are allocated.
+func (u *unmarshaler) unmarshalSection(v reflect.Value, section *IniSection) error {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+
+	if v.CanAddr() {
+		if vUnmarshalable, ok := v.Addr().Interface().(Unmarshalable); ok {
+			u.useAll(section)
+			return u.fail(vUnmarshalable.UnmarshalINI(section))
+		}
+	}
+	if vUnmarshalable, ok := v.Interface().(Unmarshalable); ok {
+		u.useAll(section)
+		return u.fail(vUnmarshalable.UnmarshalINI(section))
+	}
+
+	u.use(section, "")
+
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return u.unmarshalStruct(v, section)
+}
+
+// Unmarshals a slice or array of structs, each element is stored in it's own section.
+// Sections are either subsections named by the element index (`[server.0]`, `[server.1]`)
+// or, for the `repeat` list format, sections with the same name (`[server]`, `[server]`).
+// Slice elements are ordered by the index, with gaps between the indexes removed, array
+// elements are stored at their index.
+func (u *unmarshaler) unmarshalSectionList(v reflect.Value, finfo *fieldInfo, doc docOrSection) error {
+	if finfo.ListFormat == listFormatRepeat {
+		root, path := sectionRoot(doc, finfo.Alias)
+		sections := root.Sections(path)
+		if len(sections) == 0 {
+			// leave the preset value untouched
+			return nil
+		}
+
+		length := len(sections)
+		list := newList(v.Type(), length)
+		length = min(length, list.Len())
+
+		errCount := len(u.errs)
+		for i := 0; i < length; i++ {
+			u.push(fmt.Sprintf("[%d]", i))
+			err := u.unmarshalSection(list.Index(i), sections[i])
+			u.pop()
+			if err != nil {
+				return err
 			}
-		case reflect.Float32, reflect.Float64:
-			for _, key := range docKeys {
-				value, err := docSection.GetFloat(key)
-				if err != nil {
-					return err
-				}
-				fieldVal.SetMapIndex(
-					reflect.ValueOf(key),
-					reflect.ValueOf(value).Convert(mapElemType),
-				)
+		}
+		if len(u.errs) == errCount {
+			v.Set(list)
+		}
+		return nil
+	}
+
+	if !doc.HasSection(finfo.Alias) {
+		// leave the preset value untouched
+		return nil
+	}
+
+	parent := doc.Section(finfo.Alias)
+	u.use(parent, "")
+
+	type indexedSection struct {
+		idx  int
+		name string
+	}
+
+	sections := make([]indexedSection, 0, 8)
+	for _, name := range parent.SubsectionNames() {
+		idx, err := strconv.Atoi(name)
+		if err != nil || idx < 0 || strconv.Itoa(idx) != name {
+			continue
+		}
+		sections = append(sections, indexedSection{idx, name})
+	}
+	slices.SortFunc(sections, func(a, b indexedSection) int {
+		return cmp.Compare(a.idx, b.idx)
+	})
+
+	// slices are sized by the number of sections and not by the largest index, since
+	// the index comes from the document, gaps between the indexes are removed
+	list := newList(v.Type(), len(sections))
+
+	errCount := len(u.errs)
+	for pos, section := range sections {
+		idx := section.idx
+		if v.Kind() == reflect.Slice {
+			idx = pos
+		} else if idx >= list.Len() {
+			continue
+		}
+		u.push(fmt.Sprintf("[%d]", idx))
+		err := u.unmarshalSection(list.Index(idx), parent.Section(section.name))
+		u.pop()
+		if err != nil {
+			return err
+		}
+	}
+	if len(u.errs) == errCount {
+		v.Set(list)
+	}
+
+	return nil
+}
+
+// Parses the [values] into the elements of the [v] slice or array. Array elements
+// without a corresponding value are zeroed, values that don't fit in the array are
+// ignored. [v] is left untouched if any of the values is invalid.
+func (u *unmarshaler) unmarshalList(v reflect.Value, values []string, finfo *fieldInfo, doc docOrSection) error {
+	list := newList(v.Type(), len(values))
+	length := min(len(values), list.Len())
+
+	failed := false
+	for i := 0; i < length; i++ {
+		err := unmarshalValue(list.Index(i), values[i], finfo.Layout)
+		if err != nil {
+			failed = true
+			u.push(fmt.Sprintf("[%d]", i))
+			err = u.typeError(doc, finfo.listKey(), values[i], v.Type().Elem(), err)
+			u.pop()
+			if err != nil {
+				return err
 			}
-		case reflect.Interface:
-			for _, key := range docKeys {
-				var value any = docSection.Get(key)
-				fieldVal.SetMapIndex(
-					reflect.ValueOf(key),
-					reflect.ValueOf(value),
-				)
+		}
+	}
+	if !failed {
+		v.Set(list)
+	}
+
+	return nil
+}
+
+// Returns a new addressable slice of the given [length] or a zeroed array of type [t]
+func newList(t reflect.Type, length int) reflect.Value {
+	list := reflect.New(t).Elem()
+	if t.Kind() == reflect.Slice {
+		list.Set(reflect.MakeSlice(t, length, length))
+	}
+	return list
+}
+
+// Reports whether values of type [t] are stored as sections
+func isSectionType(t reflect.Type) bool {
+	if isValueType(t) {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// Returns the document containing the [name] section of [doc] and the full path of that section
+func sectionRoot(doc docOrSection, name string) (*IniDoc, string) {
+	switch v := doc.(type) {
+	case *IniDoc:
+		return v, name
+	case *IniSection:
+		if v.root == nil {
+			v.root = &IniDoc{}
+		}
+		if v.name != "" {
+			return v.root, fmt.Sprintf("%s.%s", v.name, name)
+		}
+		return v.root, name
+	}
+	panic("internal marshaler error: invalid doc type")
+}
+
+// Parses the string value of a key into [v], [v] must be of a bool, string, numeric
+// or an empty interface type, or implement `ValueUnmarshalable` or `encoding.TextUnmarshaler`.
+// Nil pointers to such types are allocated. The [layout] is used for `time.Time` values,
+// RFC 3339 is used if it's empty.
+func unmarshalValue(v reflect.Value, value string, layout string) error {
+	if v.Kind() == reflect.Ptr {
+		if !v.IsNil() {
+			return unmarshalValue(v.Elem(), value, layout)
+		}
+		// the pointer is only assigned once the value was parsed successfully
+		ptr := reflect.New(v.Type().Elem())
+		if err := unmarshalValue(ptr.Elem(), value, layout); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	switch v.Type() {
+	case timeType:
+		t, err := parseTime(value, layout)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		if value == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			// durations used to be stored as the number of nanoseconds
+			n, intErr := strconv.ParseInt(value, 10, 64)
+			if intErr != nil {
+				return err
 			}
+			d = time.Duration(n)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	target := v
+	if v.CanAddr() {
+		target = v.Addr()
+	}
+
+	switch u := target.Interface().(type) {
+	case ValueUnmarshalable:
+		return u.UnmarshalINIValue(value)
+	case encoding.TextUnmarshaler:
+		return u.UnmarshalText([]byte(value))
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(value == "true")
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == "" {
+			v.SetUint(0)
+			return nil
+		}
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(value))
 		}
 	}
 
 	return nil
 }
 
+// Converts [v] to the string value of a key, returns false if [v] is not of a bool,
+// string or numeric type and implements neither `ValueMarshalable` nor
+// `encoding.TextMarshaler`, or if it's a nil pointer. The [layout] is used for
+// `time.Time` values, RFC 3339 is used if it's empty.
+func marshalValue(v reflect.Value, layout string) (string, bool, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false, nil
+		}
+		return marshalValue(v.Elem(), layout)
+	}
+
+	switch v.Type() {
+	case timeType:
+		return formatTime(v.Interface().(time.Time), layout), true, nil
+	case durationType:
+		return time.Duration(v.Int()).String(), true, nil
+	}
+
+	if !v.CanAddr() {
+		// copy the value, so that methods with a pointer receiver are found as well
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	candidates := []reflect.Value{v, v.Addr()}
+
+	for _, c := range candidates {
+		if m, ok := c.Interface().(ValueMarshalable); ok {
+			value, err := m.MarshalINIValue()
+			if err != nil {
+				return "", false, err
+			}
+			return value, true, nil
+		}
+	}
+
+	for _, c := range candidates {
+		if m, ok := c.Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			if err != nil {
+				return "", false, err
+			}
+			return string(text), true, nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), true, nil
+	}
+	return "", false, nil
+}
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+)
+
+var valueInterfaceTypes = []reflect.Type{
+	reflect.TypeFor[ValueMarshalable](),
+	reflect.TypeFor[ValueUnmarshalable](),
+	reflect.TypeFor[encoding.TextMarshaler](),
+	reflect.TypeFor[encoding.TextUnmarshaler](),
+}
+
+// Reports whether values of type [t] are stored as a single key, that is [t] is
+// either a primitive type, it implements one of the value marshaler interfaces, or
+// it's a pointer to such type
+func isValueType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr && isValueType(t.Elem()) {
+		return true
+	}
+	return isValueKind(t.Kind()) || implementsValueInterface(t)
+}
+
+// Reports whether [t] or a pointer to [t] implements `ValueMarshalable`,
+// `ValueUnmarshalable`, `encoding.TextMarshaler` or `encoding.TextUnmarshaler`
+func implementsValueInterface(t reflect.Type) bool {
+	ptr := t
+	if t.Kind() != reflect.Ptr {
+		ptr = reflect.PointerTo(t)
+	}
+	for _, it := range valueInterfaceTypes {
+		if t.Implements(it) || ptr.Implements(it) {
+			return true
+		}
+	}
+	return false
+}
+
+func isValueKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
 func UnmarshalDoc(doc *IniDoc, v interface{}) error {
+	return UnmarshalDocWithOptions(doc, v, UnmarshalOptions{})
+}
+
+// Unmarshals the [doc] into the struct pointed to by [v]. Values that can't be parsed
+// are reported as `*UnmarshalTypeError` errors.
+func UnmarshalDocWithOptions(doc *IniDoc, v any, opts UnmarshalOptions) error {
 	if v == nil {
 		return fmt.Errorf("given struct is nil")
 	}
@@ -218,186 +781,19 @@ func UnmarshalDoc(doc *IniDoc, v interface{}) error {
 		return fmt.Errorf("given value is not a struct")
 	}
 
-	vFields := reflect.VisibleFields(vType)
-	for _, f := range vFields {
-		fieldInfo := parseFieldTag("ini", f)
-		err := unmarshalField(vElem, f, fieldInfo, doc)
-		if err != nil {
-			return err
-		}
+	u := unmarshaler{opts: opts}
+	u.push(vType.Name())
 
-		// switch fkind {
-		// case reflect.Bool:
-		// 	strvalue := doc.Get(fieldInfo.Alias)
-		// 	if strvalue == "true" {
-		// 		vElem.FieldByName(fieldInfo.Name).SetBool(true)
-		// 	}
-		// case reflect.String:
-		// 	value := doc.Get(fieldInfo.Alias)
-		// 	vElem.FieldByName(fieldInfo.Name).SetString(value)
-		// case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		// 	value, err := doc.GetInt(fieldInfo.Alias)
-		// 	if err != nil {
-		// 		return err
-		// 	}
-		// 	vElem.FieldByName(fieldInfo.Name).SetInt(value)
-		// case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		// 	value, err := doc.GetUint(fieldInfo.Alias)
-		// 	if err != nil {
-		// 		return err
-		// 	}
-		// 	vElem.FieldByName(fieldInfo.Name).SetUint(value)
-		// case reflect.Float32, reflect.Float64:
-		// 	value, err := doc.GetFloat(fieldInfo.Alias)
-		// 	if err != nil {
-		// 		return err
-		// 	}
-		// 	vElem.FieldByName(fieldInfo.Name).SetFloat(value)
-		// case reflect.Struct, reflect.Ptr:
-		// 	fieldVal := vElem.FieldByName(fieldInfo.Name)
-
-		// 	sectElem := fieldVal
-		// 	sectType := f.Type
-		// 	sectKind := sectType.Kind()
-
-		// 	if fieldVal.IsZero() && sectKind == reflect.Ptr {
-		// 		fieldVal.Set(reflect.New(fieldVal.Type().Elem()))
-		// 	}
-
-		// 	docSection := doc.Section(fieldInfo.Alias)
-
-		// 	vUnmarshalable, ok := fieldVal.Interface().(Unmarshalable)
-		// 	if ok {
-		// 		err := vUnmarshalable.UnmarshalINI(docSection)
-		// 		if err != nil {
-		// 			return err
-		// 		}
-		// 		continue
-		// 	}
-
-		// 	if sectKind == reflect.Ptr {
-		// 		sectType = sectType.Elem()
-		// 		sectKind = sectType.Kind()
-		// 		sectElem = fieldVal.Elem()
-		// 	}
-
-		// 	if sectKind != reflect.Struct {
-		// 		continue
-		// 	}
-
-		// 	sectFields := reflect.VisibleFields(sectType)
-		// 	for _, sectF := range sectFields {
-		// 		secFieldInfo := parseField("ini", sectF)
-		// 		switch sectF.Type.Kind() {
-		// 		case reflect.Bool:
-		// 			strvalue := docSection.Get(secFieldInfo.Alias)
-		// 			if strvalue == "true" {
-		// 				sectElem.FieldByName(secFieldInfo.Name).SetBool(true)
-		// 			}
-		// 		case reflect.String:
-		// 			value := docSection.Get(secFieldInfo.Alias)
-		// 			sectElem.FieldByName(secFieldInfo.Name).SetString(value)
-		// 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		// 			value, err := docSection.GetInt(secFieldInfo.Alias)
-		// 			if err != nil {
-		// 				return err
-		// 			}
-		// 			sectElem.FieldByName(secFieldInfo.Name).SetInt(value)
-		// 		case reflect.Float32, reflect.Float64:
-		// 			value, err := docSection.GetFloat(secFieldInfo.Alias)
-		// 			if err != nil {
-		// 				return err
-		// 			}
-		// 			sectElem.FieldByName(secFieldInfo.Name).SetFloat(value)
-		// 		}
-		// 	}
-		// case reflect.Map:
-		// 	fieldVal := vElem.FieldByName(fieldInfo.Name)
-
-		// 	keyKind := fieldVal.Type().Key().Kind()
-		// 	if keyKind != reflect.String {
-		// 		continue
-		// 	}
-
-		// 	docSection := doc.Section(fieldInfo.Alias)
-		// 	docKeys := docSection.Keys()
-
-		// 	if fieldVal.IsZero() {
-		// 		fieldVal.Set(reflect.MakeMapWithSize(
-		// 			reflect.MapOf(
-		// 				reflect.TypeOf(""),
-		// 				f.Type.Elem(),
-		// 			),
-		// 			len(docKeys),
-		// 		))
-		// 	}
-
-		// 	mapElemType := f.Type.Elem()
-		// 	switch mapElemType.Kind() {
-		// 	case reflect.String:
-		// 		for _, key := range docKeys {
-		// 			value := docSection.Get(key)
-		// 			fieldVal.SetMapIndex(
-		// 				reflect.ValueOf(key),
-		// 				reflect.ValueOf(value),
-		// 			)
-		// 		}
-		// 	case reflect.Bool:
-		// 		for _, key := range docKeys {
-		// 			value, err := docSection.GetBool(key)
-		// 			if err != nil {
-		// 				return err
-		// 			}
-		// 			fieldVal.SetMapIndex(
-		// 				reflect.ValueOf(key),
-		// 				reflect.ValueOf(value),
-		// 			)
-		// 		}
-		// 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		// 		for _, key := range docKeys {
-		// 			value, err := docSection.GetInt(key)
-		// 			if err != nil {
-		// 				return err
-		// 			}
-		// 			fieldVal.SetMapIndex(
-		// 				reflect.ValueOf(key),
-		// 				reflect.ValueOf(value).Convert(mapElemType),
-		// 			)
-		// 		}
-		// 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		// 		for _, key := range docKeys {
-		// 			value, err := docSection.GetUint(key)
-		// 			if err != nil {
-		// 				return err
-		// 			}
-		// 			fieldVal.SetMapIndex(
-		// 				reflect.ValueOf(key),
-		// 				reflect.ValueOf(value).Convert(mapElemType),
-		// 			)
-		// 		}
-		// 	case reflect.Float32, reflect.Float64:
-		// 		for _, key := range docKeys {
-		// 			value, err := docSection.GetFloat(key)
-		// 			if err != nil {
-		// 				return err
-		// 			}
-		// 			fieldVal.SetMapIndex(
-		// 				reflect.ValueOf(key),
-		// 				reflect.ValueOf(value).Convert(mapElemType),
-		// 			)
-		// 		}
-		// 	case reflect.Interface:
-		// 		for _, key := range docKeys {
-		// 			var value any = docSection.Get(key)
-		// 			fieldVal.SetMapIndex(
-		// 				reflect.ValueOf(key),
-		// 				reflect.ValueOf(value),
-		// 			)
-		// 		}
-		// 	}
-		// }
+	err := u.unmarshalStruct(vElem, doc)
+	if err != nil {
+		return err
+	}
+	if opts.DisallowUnknown {
+		u.errs = append(u.errs, u.unknownKeys(doc)...)
+	}
+	if len(u.errs) > 0 {
+		return u.errs
 	}
-
 	return nil
 }
 
@@ -406,91 +802,66 @@ func Unmarshal(data string, v interface{}) error {
 	return UnmarshalDoc(doc, v)
 }
 
-func marshalField(strct reflect.Value, field reflect.StructField, finfo *fieldInfo, doc DocOrSection) error {
-	switch field.Type.Kind() {
-	case reflect.String:
-		value := strct.FieldByName(finfo.Name).String()
-		doc.Set(finfo.Alias, value)
-	case reflect.Bool:
-		value := strct.FieldByName(finfo.Name).Bool()
-		doc.SetBool(finfo.Alias, value)
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		value := strct.FieldByName(finfo.Name).Int()
-		doc.SetInt(finfo.Alias, value)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		value := strct.FieldByName(finfo.Name).Uint()
-		doc.SetUint(finfo.Alias, value)
-	case reflect.Float32, reflect.Float64:
-		value := strct.FieldByName(finfo.Name).Float()
-		doc.SetFloat(finfo.Alias, value)
-	case reflect.Struct, reflect.Ptr:
-		fieldVal := strct.FieldByName(finfo.Name)
-		sectElem := fieldVal
-		sectType := field.Type
-		sectKind := sectType.Kind()
+func UnmarshalWithOptions(data string, v any, opts UnmarshalOptions) error {
+	doc, err := ParseWithOptions(data, opts.Parse)
+	if err != nil {
+		return err
+	}
+	return UnmarshalDocWithOptions(doc, v, opts)
+}
 
-		if fieldVal.IsZero() && sectKind == reflect.Ptr {
-			return nil
-		}
+func marshalField(fieldVal reflect.Value, field reflect.StructField, finfo *fieldInfo, doc docOrSection) error {
+	if finfo.Skip {
+		return nil
+	}
 
-		vUnmarshalable, ok := fieldVal.Interface().(Marshalable)
-		if ok {
-			secOrDoc, err := vUnmarshalable.MarshalINI()
-			if err != nil {
-				return err
-			}
+	if finfo.OmitEmpty && isEmptyValue(fieldVal) {
+		return nil
+	}
 
-			section, ok := secOrDoc.(*IniSection)
-			if ok {
-				if section.name == "" {
-					section.name = finfo.Alias
-				}
-				switch v := doc.(type) {
-				case *IniDoc:
-					v.putSection(section)
-				case *IniSection:
-					v.putSubSection(section)
-				default:
-					panic("internal marshaler error: invalid doc type")
-				}
-				return nil
-			}
+	kind := field.Type.Kind()
 
-			asDoc, ok := secOrDoc.(*IniDoc)
-			if ok {
-				section := docToSection(asDoc)
-				section.name = finfo.Alias
-				switch v := doc.(type) {
-				case *IniDoc:
-					v.putSection(section)
-				case *IniSection:
-					v.putSubSection(section)
-				default:
-					panic("internal marshaler error: invalid doc type")
-				}
-				return nil
-			}
+	if isValueType(field.Type) {
+		value, ok, err := marshalValue(fieldVal, finfo.Layout)
+		if err != nil {
+			return err
+		}
+		if ok {
+			doc.Set(finfo.Alias, value)
 		}
+		return nil
+	}
 
-		if sectKind == reflect.Ptr {
-			sectType = sectType.Elem()
-			sectKind = sectType.Kind()
-			sectElem = fieldVal.Elem()
+	switch kind {
+	case reflect.Slice, reflect.Array:
+		if isSectionType(field.Type.Elem()) {
+			return marshalSectionList(fieldVal, finfo, doc)
 		}
 
-		if sectKind != reflect.Struct {
+		if !isValueType(field.Type.Elem()) {
 			return nil
 		}
 
-		docSection := doc.Section(finfo.Alias)
+		values := make([]string, 0, fieldVal.Len())
+		for i := 0; i < fieldVal.Len(); i++ {
+			value, _, err := marshalValue(fieldVal.Index(i), finfo.Layout)
+			if err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
 
-		sectFields := reflect.VisibleFields(sectType)
-		for _, sectF := range sectFields {
-			sectFieldInfo := parseFieldTag("ini", sectF)
-			marshalField(sectElem, sectF, sectFieldInfo, docSection)
+		switch finfo.ListFormat {
+		case listFormatComma:
+			doc.Set(finfo.Alias, joinList(values))
+		case listFormatRepeat, listFormatBrackets:
+			for _, value := range values {
+				doc.AddValue(finfo.listKey(), value)
+			}
 		}
+	case reflect.Struct, reflect.Ptr:
+		return marshalSection(fieldVal, doc, finfo.Alias)
 	case reflect.Map:
-		fieldVal := strct.FieldByName(finfo.Name)
 
 		if fieldVal.IsZero() {
 			return nil
@@ -508,6 +879,17 @@ func marshalField(strct reflect.Value, field reflect.StructField, finfo *fieldIn
 			return strings.Compare(a.String(), b.String())
 		})
 
+		if isSectionType(field.Type.Elem()) {
+			// each map element is stored in a subsection
+			for _, key := range mapKeys {
+				err := marshalSection(fieldVal.MapIndex(key), docSection, key.String())
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
 		for _, key := range mapKeys {
 			value := fieldVal.MapIndex(key)
 			valueKind := value.Kind()
@@ -517,23 +899,12 @@ func marshalField(strct reflect.Value, field reflect.StructField, finfo *fieldIn
 				valueKind = value.Kind()
 			}
 
-			keyV := key.String()
-			switch valueKind {
-			case reflect.String:
-				value := value.String()
-				docSection.Set(keyV, value)
-			case reflect.Bool:
-				value := value.Bool()
-				docSection.SetBool(keyV, value)
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				value := value.Int()
-				docSection.SetInt(keyV, value)
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				value := value.Uint()
-				docSection.SetUint(keyV, value)
-			case reflect.Float32, reflect.Float64:
-				value := value.Float()
-				docSection.SetFloat(keyV, value)
+			strValue, ok, err := marshalValue(value, finfo.Layout)
+			if err != nil {
+				return err
+			}
+			if ok {
+				docSection.Set(key.String(), strValue)
 			}
 		}
 	}
@@ -541,6 +912,107 @@ func marshalField(strct reflect.Value, field reflect.StructField, finfo *fieldIn
 	return nil
 }
 
+// Marshals all fields of the [v] struct into the given document or section, fields of
+// nil embedded structs are skipped
+func marshalStruct(v reflect.Value, doc docOrSection) error {
+	for _, f := range structFields(v.Type()) {
+		fieldVal, ok := fieldByIndex(v, f.index, false)
+		if !ok {
+			continue
+		}
+		err := marshalField(fieldVal, f.field, f.info, doc)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Marshals [v], which is either a struct, a pointer to a struct or a `Marshalable`,
+// into the [name] section of [doc]. Nil pointers are skipped.
+func marshalSection(v reflect.Value, doc docOrSection, name string) error {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+
+	vMarshalable, ok := v.Interface().(Marshalable)
+	if ok {
+		secOrDoc, err := vMarshalable.MarshalINI()
+		if err != nil {
+			return err
+		}
+
+		var section *IniSection
+		switch r := secOrDoc.(type) {
+		case *IniSection:
+			// the marshaler may return a shared section, don't modify it
+			section = r.Clone()
+			if section.name == "" {
+				section.name = name
+			}
+		case *IniDoc:
+			section = docToSection(r)
+			section.name = name
+		default:
+			return nil
+		}
+
+		switch d := doc.(type) {
+		case *IniDoc:
+			d.putSection(section)
+		case *IniSection:
+			d.putSubSection(section)
+		default:
+			panic("internal marshaler error: invalid doc type")
+		}
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return marshalStruct(v, doc.Section(name))
+}
+
+// Marshals a slice or array of structs, see `unmarshalSectionList` for the layout of
+// the sections
+func marshalSectionList(v reflect.Value, finfo *fieldInfo, doc docOrSection) error {
+	if finfo.ListFormat == listFormatRepeat {
+		root, path := sectionRoot(doc, finfo.Alias)
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			if elem.Kind() == reflect.Ptr {
+				if elem.IsNil() {
+					continue
+				}
+				elem = elem.Elem()
+			}
+			if _, ok := v.Index(i).Interface().(Marshalable); ok {
+				return fmt.Errorf("custom marshaler of %s can't be used with repeated sections ('%s')", elem.Type(), finfo.Alias)
+			}
+			err := marshalStruct(elem, root.appendSection(path))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	parent := doc.Section(finfo.Alias)
+	for i := 0; i < v.Len(); i++ {
+		err := marshalSection(v.Index(i), parent, strconv.Itoa(i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func MarshalDoc(v any) (*IniDoc, error) {
 	if v == nil {
 		return nil, fmt.Errorf("given struct is nil")
@@ -549,14 +1021,21 @@ func MarshalDoc(v any) (*IniDoc, error) {
 	vUnmarshalable, ok := v.(Marshalable)
 	if ok {
 		doc, err := vUnmarshalable.MarshalINI()
+		if err != nil {
+			return nil, err
+		}
 		switch v := doc.(type) {
 		case *IniSection:
+			var result *IniDoc
 			if v.root != nil {
-				v.root.lines = v.lines
-				return v.root, err
+				result = v.root.Clone()
+			} else {
+				result = NewDoc()
 			}
+			result.lines = slices.Clone(v.lines)
+			return result, nil
 		case *IniDoc:
-			return v, err
+			return v, nil
 		default:
 			panic("MarshalINI returned neither doc or section")
 		}
@@ -577,13 +1056,9 @@ func MarshalDoc(v any) (*IniDoc, error) {
 		return nil, fmt.Errorf("given value is not a struct")
 	}
 
-	vFields := reflect.VisibleFields(vType)
-	for _, f := range vFields {
-		fieldInfo := parseFieldTag("ini", f)
-		err := marshalField(vElem, f, fieldInfo, doc)
-		if err != nil {
-			return nil, err
-		}
+	err := marshalStruct(vElem, doc)
+	if err != nil {
+		return nil, err
 	}
 
 	return doc, nil
@@ -601,32 +1076,116 @@ type fieldInfo struct {
 	Alias string
 
 	Name string
+
+	// Field is ignored when marshaling and unmarshaling (`ini:"-"` tag or an unexported field)
+	Skip bool
+
+	// Field is not marshaled if it has an empty value (`omitempty` tag option)
+	OmitEmpty bool
+
+	// Unmarshaling fails if the key or section is missing (`required` tag option)
+	Required bool
+
+	// Value used when unmarshaling if the key is missing (`default` tag)
+	Default    string
+	HasDefault bool
+
+	// How slice and array values are stored (`comma`, `repeat` or `brackets` tag option)
+	ListFormat int
+
+	// Format of `time.Time` values (`layout=<layout>` tag option), it has to be the last
+	// option since layouts can contain commas
+	Layout string
+
+	// Alias was given in the tag
+	Tagged bool
+
+	// Fields of the struct are stored in the parent section (`inline` tag option)
+	Inline bool
+
+	// Embedded struct is stored in it's own section (`section` tag option)
+	Section bool
+}
+
+const (
+	// all values in a single key, separated by commas (`hosts=a,b`)
+	listFormatComma = iota
+	// one key for each value (`host=a`, `host=b`)
+	listFormatRepeat
+	// one key for each value, with brackets appended to the key (`host[]=a`, `host[]=b`)
+	listFormatBrackets
+)
+
+// Returns the key under which the list values are stored
+func (f *fieldInfo) listKey() string {
+	if f.ListFormat == listFormatBrackets {
+		return f.Alias + "[]"
+	}
+	return f.Alias
 }
 
 // ParseField parses [FieldInfo] for the given struct field [f] from struct tag with name [tagName]
 func parseFieldTag(tagName string, f reflect.StructField) *fieldInfo {
-	var parts []string
-	alias := f.Name
+	info := &fieldInfo{
+		Alias: f.Name,
+		Name:  f.Name,
+		Skip:  !f.IsExported(),
+	}
+
+	info.Default, info.HasDefault = f.Tag.Lookup("default")
 
 	tag, tagOk := f.Tag.Lookup(tagName)
-	if tagOk {
-		partsTemp := strings.Split(tag, ",")
-		parts = make([]string, 0, len(partsTemp))
-		for i := 0; i < len(partsTemp); i++ {
-			part := strings.TrimSpace(partsTemp[i])
-			if len(part) != 0 {
-				parts = append(parts, part)
-			}
-		}
+	if !tagOk {
+		return info
 	}
 
-	if len(parts) != 0 {
-		alias = parts[0]
-		// TODO parse other tags
+	if tag == "-" {
+		info.Skip = true
+		return info
 	}
 
-	return &fieldInfo{
-		Alias: alias,
-		Name:  f.Name,
+	parts := strings.Split(tag, ",")
+
+	alias := strings.TrimSpace(parts[0])
+	if len(alias) != 0 {
+		info.Alias = alias
+		info.Tagged = true
+	}
+
+	for idx, part := range parts[1:] {
+		if layout, ok := strings.CutPrefix(strings.TrimLeft(part, " "), "layout="); ok {
+			info.Layout = strings.Join(append([]string{layout}, parts[idx+2:]...), ",")
+			break
+		}
+
+		switch strings.TrimSpace(part) {
+		case "omitempty":
+			info.OmitEmpty = true
+		case "required":
+			info.Required = true
+		case "comma":
+			info.ListFormat = listFormatComma
+		case "repeat":
+			info.ListFormat = listFormatRepeat
+		case "brackets":
+			info.ListFormat = listFormatBrackets
+		case "inline":
+			info.Inline = true
+		case "section":
+			info.Section = true
+		}
+	}
+
+	return info
+}
+
+// Reports whether the value is considered empty for the `omitempty` option
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
 	}
+	return v.IsZero()
 }
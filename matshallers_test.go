@@ -1,7 +1,14 @@
 package ini_test
 
 import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ncpa0cpl/ini"
 )
@@ -845,3 +852,987 @@ K2=23
 K=reeee
 `)
 }
+
+func TestMarshalTagOptions(t *testing.T) {
+	expect := expect(t)
+
+	type Server struct {
+		Host string `ini:"host"`
+		Port int    `ini:"port,omitempty"`
+	}
+
+	type Config struct {
+		Name     string            `ini:"name"`
+		Secret   string            `ini:"-"`
+		Dash     string            `ini:"-,"`
+		Comment  string            `ini:",omitempty"`
+		Debug    bool              `ini:"debug,omitempty"`
+		Labels   map[string]string `ini:"labels,omitempty"`
+		Server   Server            `ini:"server"`
+		Optional *Server           `ini:"optional,omitempty"`
+		internal string
+	}
+
+	str, err := ini.Marshal(Config{
+		Name:     "app",
+		Secret:   "hunter2",
+		Dash:     "dash",
+		Labels:   map[string]string{},
+		Server:   Server{Host: "localhost"},
+		internal: "internal",
+	})
+	expect(err).NoErr()
+
+	expect(str).ToBe(`name=app
+-=dash
+
+[server]
+host=localhost
+`)
+}
+
+func TestUnmarshalTagOptions(t *testing.T) {
+	expect := expect(t)
+
+	type Server struct {
+		Host string `ini:"host,required"`
+		Port int    `ini:"port"`
+	}
+
+	type Config struct {
+		Name   string `ini:"name,required"`
+		Secret string `ini:"-"`
+		Server Server `ini:"server,required"`
+	}
+
+	cfg := Config{}
+	expect(ini.Unmarshal("name=app\nSecret=x\n-=y\n[server]\nhost=localhost\n", &cfg)).NoErr()
+	expect(cfg).ToBe(Config{Name: "app", Server: Server{Host: "localhost"}})
+
+	var reqErr *ini.RequiredError
+
+	err := ini.Unmarshal("[server]\nhost=localhost\n", &Config{})
+	expect(err.Error()).ToBe("required key 'name' is missing")
+	expect(errors.As(err, &reqErr)).ToBe(true)
+	expect(*reqErr).ToBe(ini.RequiredError{Key: "name"})
+
+	err = ini.Unmarshal("name=app\n", &Config{})
+	expect(err.Error()).ToBe("required section [server] is missing")
+	expect(errors.As(err, &reqErr)).ToBe(true)
+	expect(*reqErr).ToBe(ini.RequiredError{Section: "server"})
+
+	err = ini.Unmarshal("name=app\n[server]\nport=80\n", &Config{})
+	expect(err.Error()).ToBe("required key 'host' in section [server] is missing")
+	expect(errors.As(err, &reqErr)).ToBe(true)
+	expect(*reqErr).ToBe(ini.RequiredError{Section: "server", Key: "host"})
+
+	type Outer struct {
+		Config Config `ini:"config"`
+	}
+
+	err = ini.Unmarshal("[config]\nname=app\n", &Outer{})
+	expect(err.Error()).ToBe("required section [config.server] is missing")
+}
+
+func TestUnmarshalDefaults(t *testing.T) {
+	expect := expect(t)
+
+	type Server struct {
+		Host    string  `ini:"host" default:"localhost"`
+		Port    uint16  `ini:"port" default:"8080"`
+		Debug   bool    `ini:"debug" default:"true"`
+		Ratio   float32 `ini:"ratio" default:"0.5"`
+		Workers int     `ini:"workers"`
+	}
+
+	type Config struct {
+		Name   string `ini:"name" default:"app"`
+		Server Server `ini:"server"`
+	}
+
+	cfg := Config{}
+	expect(ini.Unmarshal("[server]\nport=9090\ndebug=false\n", &cfg)).NoErr()
+	expect(cfg).ToBe(Config{
+		Name: "app",
+		Server: Server{
+			Host:  "localhost",
+			Port:  9090,
+			Debug: false,
+			Ratio: 0.5,
+		},
+	})
+
+	// keys that are missing and have no default value leave the preset values untouched
+	preset := Config{Name: "preset", Server: Server{Host: "example.com", Workers: 4}}
+	expect(ini.Unmarshal("name=\n[server]\nhost=\n", &preset)).NoErr()
+	expect(preset.Name).ToBe("")
+	expect(preset.Server.Host).ToBe("")
+	expect(preset.Server.Workers).ToBe(4)
+
+	type Invalid struct {
+		Port int `ini:"port" default:"eighty"`
+	}
+	expect(ini.Unmarshal("", &Invalid{}) != nil).ToBe(true)
+
+	type Small struct {
+		N int8    `ini:"n"`
+		U uint8   `ini:"u"`
+		F float32 `ini:"f"`
+	}
+	expect(ini.Unmarshal("n=300", &Small{}) != nil).ToBe(true)
+	expect(ini.Unmarshal("u=256", &Small{}) != nil).ToBe(true)
+	expect(ini.Unmarshal("f=1e300", &Small{}) != nil).ToBe(true)
+
+	// bool values other than `true` are false and never an error
+	type Flags struct {
+		Debug bool `ini:"debug"`
+	}
+	flags := Flags{Debug: true}
+	expect(ini.Unmarshal("debug=yes", &flags)).NoErr()
+	expect(flags.Debug).ToBe(false)
+}
+
+func TestMarshalLists(t *testing.T) {
+	expect := expect(t)
+
+	type Config struct {
+		Hosts   []string   `ini:"hosts"`
+		Ports   []int      `ini:"port,repeat"`
+		Tags    []string   `ini:"tag,brackets"`
+		Weights [3]float64 `ini:"weights"`
+		Empty   []string   `ini:"empty,omitempty"`
+	}
+
+	cfg := Config{
+		Hosts:   []string{"a.example.com", "b, with comma", `"quoted"`},
+		Ports:   []int{80, 443},
+		Tags:    []string{"web", "public"},
+		Weights: [3]float64{0.5, 1, 2.25},
+	}
+
+	str, err := ini.Marshal(cfg)
+	expect(err).NoErr()
+	expect(str).ToBe(`hosts=a.example.com,"b, with comma","""quoted"""
+port=80
+port=443
+tag[]=web
+tag[]=public
+weights=0.5,1,2.25
+`)
+
+	doc, err := ini.MarshalDoc(cfg)
+	expect(err).NoErr()
+
+	result := Config{}
+	expect(ini.UnmarshalDoc(doc, &result)).NoErr()
+	expect(result).ToBe(cfg)
+
+	// brackets lists are kept when parsing
+	result = Config{}
+	expect(ini.Unmarshal(str, &result)).NoErr()
+	expect(result.Hosts).ToBe(cfg.Hosts)
+	expect(result.Tags).ToBe(cfg.Tags)
+	expect(result.Weights).ToBe(cfg.Weights)
+	// repeat lists need the keep-all policy, otherwise the last value wins
+	expect(result.Ports).ToBe([]int{443})
+
+	// repeated keys are kept with the keep-all policy
+	parsed, err := ini.ParseWithOptions(str, ini.ParseOptions{DuplicateKeys: ini.DuplicateKeysKeepAll})
+	expect(err).NoErr()
+	result = Config{}
+	expect(ini.UnmarshalDoc(parsed, &result)).NoErr()
+	expect(result).ToBe(cfg)
+
+	// Marshal -> Unmarshal round trip
+	result = Config{}
+	expect(ini.UnmarshalWithOptions(str, &result, ini.UnmarshalOptions{
+		Parse: ini.ParseOptions{DuplicateKeys: ini.DuplicateKeysKeepAll},
+	})).NoErr()
+	expect(result).ToBe(cfg)
+
+	var parseErrs ini.ParseErrors
+	err = ini.UnmarshalWithOptions("[broken", &result, ini.UnmarshalOptions{Parse: ini.ParseOptions{Strict: true}})
+	expect(errors.As(err, &parseErrs)).ToBe(true)
+}
+
+func TestUnmarshalLists(t *testing.T) {
+	expect := expect(t)
+
+	type Config struct {
+		Hosts   []string  `ini:"hosts"`
+		Ids     []uint8   `ini:"ids"`
+		Short   [2]int    `ini:"short"`
+		Empty   []string  `ini:"empty"`
+		Preset  []string  `ini:"preset"`
+		Default []float32 `ini:"default" default:"1.5, 2"`
+	}
+
+	cfg := Config{Preset: []string{"kept"}}
+	expect(ini.Unmarshal(`hosts = a , "b,c" ,, d
+ids=1,2,3
+short=1,2,3
+empty=
+`, &cfg)).NoErr()
+
+	expect(cfg).ToBe(Config{
+		Hosts:   []string{"a", "b,c", "", "d"},
+		Ids:     []uint8{1, 2, 3},
+		Short:   [2]int{1, 2},
+		Empty:   []string{},
+		Preset:  []string{"kept"},
+		Default: []float32{1.5, 2},
+	})
+
+	expect(ini.Unmarshal("ids=1,x", &cfg) != nil).ToBe(true)
+}
+
+func TestMarshalSectionCollections(t *testing.T) {
+	expect := expect(t)
+
+	type User struct {
+		Name  string `ini:"name"`
+		Admin bool   `ini:"admin"`
+	}
+
+	type Server struct {
+		Host string `ini:"host"`
+		Port int    `ini:"port"`
+	}
+
+	type Config struct {
+		Users   map[string]User    `ini:"user"`
+		Groups  map[string]*User   `ini:"group"`
+		Servers []Server           `ini:"server"`
+		Backups [2]*Server         `ini:"backup"`
+		Mirrors []Server           `ini:"mirror,repeat"`
+		Extra   map[string]*Server `ini:"extra,omitempty"`
+	}
+
+	cfg := Config{
+		Users: map[string]User{
+			"bob":   {Name: "Bob"},
+			"alice": {Name: "Alice", Admin: true},
+		},
+		Groups: map[string]*User{
+			"root": {Name: "root", Admin: true},
+		},
+		Servers: []Server{{Host: "a", Port: 80}, {Host: "b", Port: 81}},
+		Backups: [2]*Server{nil, {Host: "c", Port: 82}},
+		Mirrors: []Server{{Host: "m1", Port: 1}, {Host: "m2", Port: 2}},
+	}
+
+	doc, err := ini.MarshalDoc(cfg)
+	expect(err).NoErr()
+	expect(doc.ToString()).ToBe(`[user.alice]
+name=Alice
+admin=true
+
+[user.bob]
+name=Bob
+admin=false
+
+[group.root]
+name=root
+admin=true
+
+[server.0]
+host=a
+port=80
+
+[server.1]
+host=b
+port=81
+
+[backup.1]
+host=c
+port=82
+
+[mirror]
+host=m1
+port=1
+
+[mirror]
+host=m2
+port=2
+`)
+
+	result := Config{}
+	expect(ini.UnmarshalDoc(doc, &result)).NoErr()
+	expect(result.Users).ToBe(cfg.Users)
+	expect(*result.Groups["root"]).ToBe(*cfg.Groups["root"])
+	expect(result.Servers).ToBe(cfg.Servers)
+	expect(result.Backups[0]).ToBe((*Server)(nil))
+	expect(*result.Backups[1]).ToBe(*cfg.Backups[1])
+	expect(result.Mirrors).ToBe(cfg.Mirrors)
+}
+
+func TestUnmarshalIndexedSections(t *testing.T) {
+	expect := expect(t)
+
+	type Server struct {
+		Host string `ini:"host"`
+	}
+
+	type Config struct {
+		Servers []Server  `ini:"server"`
+		Short   [1]Server `ini:"short"`
+		Preset  []Server  `ini:"preset"`
+	}
+
+	cfg := Config{Preset: []Server{{Host: "kept"}}}
+	expect(ini.Unmarshal(`
+[server.2]
+host=c
+
+[server.0]
+host=a
+
+[server.name]
+host=ignored
+
+[short.0]
+host=x
+
+[short.1]
+host=y
+`, &cfg)).NoErr()
+
+	expect(cfg).ToBe(Config{
+		Servers: []Server{{Host: "a"}, {Host: "c"}},
+		Short:   [1]Server{{Host: "x"}},
+		Preset:  []Server{{Host: "kept"}},
+	})
+
+	// huge indexes don't allocate huge slices
+	cfg = Config{}
+	expect(ini.Unmarshal("[server.900000000000]\nhost=a\n\n[short.900000000000]\nhost=b\n", &cfg)).NoErr()
+	expect(cfg.Servers).ToBe([]Server{{Host: "a"}})
+	expect(cfg.Short).ToBe([1]Server{})
+}
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+)
+
+func (l Level) MarshalText() ([]byte, error) {
+	switch l {
+	case LevelDebug:
+		return []byte("debug"), nil
+	case LevelInfo:
+		return []byte("info"), nil
+	}
+	return nil, fmt.Errorf("invalid level %d", int(l))
+}
+
+func (l *Level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = LevelDebug
+	case "info":
+		*l = LevelInfo
+	default:
+		return fmt.Errorf("invalid level '%s'", text)
+	}
+	return nil
+}
+
+func TestMarshalTextValues(t *testing.T) {
+	expect := expect(t)
+
+	type Server struct {
+		Addr    netip.Addr `ini:"addr"`
+		Started time.Time  `ini:"started"`
+	}
+
+	type Config struct {
+		Level   Level                 `ini:"level"`
+		IP      net.IP                `ini:"ip"`
+		Big     *big.Int              `ini:"big"`
+		Missing *big.Int              `ini:"missing"`
+		Allowed []netip.Addr          `ini:"allowed"`
+		Levels  map[string]Level      `ini:"levels"`
+		Hosts   map[string]netip.Addr `ini:"hosts"`
+		Server  Server                `ini:"server"`
+	}
+
+	n, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	cfg := Config{
+		Level:   LevelInfo,
+		IP:      net.ParseIP("10.0.0.1"),
+		Big:     n,
+		Allowed: []netip.Addr{netip.MustParseAddr("::1"), netip.MustParseAddr("127.0.0.1")},
+		Levels:  map[string]Level{"db": LevelDebug},
+		Hosts:   map[string]netip.Addr{"local": netip.MustParseAddr("127.0.0.1")},
+		Server: Server{
+			Addr:    netip.MustParseAddr("192.168.0.1"),
+			Started: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+
+	str, err := ini.Marshal(cfg)
+	expect(err).NoErr()
+	expect(str).ToBe(`level=info
+ip=10.0.0.1
+big=123456789012345678901234567890
+allowed=::1,127.0.0.1
+
+[levels]
+db=debug
+
+[hosts]
+local=127.0.0.1
+
+[server]
+addr=192.168.0.1
+started=2024-01-02T03:04:05Z
+`)
+
+	result := Config{}
+	expect(ini.Unmarshal(str, &result)).NoErr()
+	expect(result.Level).ToBe(cfg.Level)
+	expect(result.IP.Equal(cfg.IP)).ToBe(true)
+	expect(result.Big.Cmp(cfg.Big)).ToBe(0)
+	expect(result.Missing == nil).ToBe(true)
+	expect(result.Allowed).ToBe(cfg.Allowed)
+	expect(result.Levels).ToBe(cfg.Levels)
+	expect(result.Hosts).ToBe(cfg.Hosts)
+	expect(result.Server.Addr).ToBe(cfg.Server.Addr)
+	expect(result.Server.Started.Equal(cfg.Server.Started)).ToBe(true)
+
+	expect(ini.Unmarshal("level=verbose", &result) != nil).ToBe(true)
+
+	_, err = ini.Marshal(Config{Level: Level(7)})
+	expect(err != nil).ToBe(true)
+}
+
+type ByteSize uint64
+
+func (b ByteSize) MarshalINIValue() (string, error) {
+	if b%(1<<20) == 0 {
+		return fmt.Sprintf("%dMB", b>>20), nil
+	}
+	return fmt.Sprintf("%d", uint64(b)), nil
+}
+
+func (b *ByteSize) UnmarshalINIValue(value string) error {
+	var n uint64
+	var unit string
+	fmt.Sscanf(value, "%d%s", &n, &unit)
+	switch unit {
+	case "":
+		*b = ByteSize(n)
+	case "MB":
+		*b = ByteSize(n << 20)
+	default:
+		return fmt.Errorf("invalid size '%s'", value)
+	}
+	return nil
+}
+
+// implements both interfaces, the ini one wins
+type Flag bool
+
+func (f Flag) MarshalText() ([]byte, error) { return []byte("text"), nil }
+
+func (f Flag) MarshalINIValue() (string, error) {
+	if f {
+		return "on", nil
+	}
+	return "off", nil
+}
+
+func (f *Flag) UnmarshalText(text []byte) error { return fmt.Errorf("UnmarshalText called") }
+
+func (f *Flag) UnmarshalINIValue(value string) error {
+	*f = value == "on"
+	return nil
+}
+
+func TestMarshalCustomValues(t *testing.T) {
+	expect := expect(t)
+
+	type Config struct {
+		Cache   ByteSize            `ini:"cache"`
+		Buffer  *ByteSize           `ini:"buffer"`
+		Limits  []ByteSize          `ini:"limits"`
+		Enabled Flag                `ini:"enabled"`
+		Quotas  map[string]ByteSize `ini:"quotas"`
+	}
+
+	buffer := ByteSize(512)
+	cfg := Config{
+		Cache:   10 << 20,
+		Buffer:  &buffer,
+		Limits:  []ByteSize{1 << 20, 100},
+		Enabled: true,
+		Quotas:  map[string]ByteSize{"alice": 2 << 20},
+	}
+
+	str, err := ini.Marshal(cfg)
+	expect(err).NoErr()
+	expect(str).ToBe(`cache=10MB
+buffer=512
+limits=1MB,100
+enabled=on
+
+[quotas]
+alice=2MB
+`)
+
+	result := Config{}
+	expect(ini.Unmarshal(str, &result)).NoErr()
+	expect(result.Cache).ToBe(cfg.Cache)
+	expect(*result.Buffer).ToBe(buffer)
+	expect(result.Limits).ToBe(cfg.Limits)
+	expect(result.Enabled).ToBe(cfg.Enabled)
+	expect(result.Quotas).ToBe(cfg.Quotas)
+
+	expect(ini.Unmarshal("cache=10GB", &result) != nil).ToBe(true)
+
+	// pointer receiver methods are used for values that are not addressable
+	type Numbers struct {
+		Big  big.Int            `ini:"big"`
+		Bigs map[string]big.Int `ini:"bigs"`
+	}
+
+	numbers := Numbers{Big: *big.NewInt(7), Bigs: map[string]big.Int{"a": *big.NewInt(8)}}
+	str, err = ini.Marshal(numbers)
+	expect(err).NoErr()
+	expect(str).ToBe("big=7\n\n[bigs]\na=8\n")
+
+	str, err = ini.Marshal(&numbers)
+	expect(err).NoErr()
+	expect(str).ToBe("big=7\n\n[bigs]\na=8\n")
+}
+
+func TestMarshalTimeFields(t *testing.T) {
+	expect := expect(t)
+
+	type Config struct {
+		Timeout  time.Duration            `ini:"timeout"`
+		Retries  []time.Duration          `ini:"retries"`
+		Created  time.Time                `ini:"created"`
+		Day      time.Time                `ini:"day,omitempty,layout=2006-01-02"`
+		Stamp    *time.Time               `ini:"stamp,layout=Mon, 02 Jan 2006"`
+		Limits   map[string]time.Duration `ini:"limits"`
+		Defaults time.Duration            `ini:"defaults" default:"5m"`
+	}
+
+	stamp := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	cfg := Config{
+		Timeout: 30 * time.Second,
+		Retries: []time.Duration{time.Second, 2 * time.Minute},
+		Created: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+		Day:     stamp,
+		Stamp:   &stamp,
+		Limits:  map[string]time.Duration{"read": 500 * time.Millisecond},
+	}
+
+	str, err := ini.Marshal(cfg)
+	expect(err).NoErr()
+	expect(str).ToBe(`timeout=30s
+retries=1s,2m0s
+created=2024-03-01T12:30:00Z
+day=2024-03-01
+stamp=Fri, 01 Mar 2024
+defaults=0s
+
+[limits]
+read=500ms
+`)
+
+	result := Config{}
+	expect(ini.Unmarshal(strings.Replace(str, "defaults=0s\n", "", 1), &result)).NoErr()
+	expect(result.Timeout).ToBe(cfg.Timeout)
+	expect(result.Retries).ToBe(cfg.Retries)
+	expect(result.Created.Equal(cfg.Created)).ToBe(true)
+	expect(result.Day.Equal(cfg.Day)).ToBe(true)
+	expect(result.Stamp.Equal(stamp)).ToBe(true)
+	expect(result.Limits).ToBe(cfg.Limits)
+	expect(result.Defaults).ToBe(5 * time.Minute)
+
+	// plain numbers are read as nanoseconds
+	expect(ini.Unmarshal("timeout=1000", &result)).NoErr()
+	expect(result.Timeout).ToBe(time.Microsecond)
+
+	expect(ini.Unmarshal("timeout=soon", &result) != nil).ToBe(true)
+	expect(ini.Unmarshal("day=01.03.2024", &result) != nil).ToBe(true)
+}
+
+type Common struct {
+	Name  string `ini:"name"`
+	Debug bool   `ini:"debug"`
+}
+
+type Timestamps struct {
+	Created string `ini:"created"`
+}
+
+type meta struct {
+	Owner string `ini:"owner"`
+}
+
+func TestMarshalEmbeddedStructs(t *testing.T) {
+	expect := expect(t)
+
+	type Limits struct {
+		Max int `ini:"max"`
+	}
+
+	type Config struct {
+		Common
+		*Timestamps
+		meta
+		Limits `ini:",section"`
+		Extra  Limits `ini:"extra,inline"`
+		Debug  string `ini:"debug"`
+	}
+
+	cfg := Config{
+		Common:     Common{Name: "app", Debug: true},
+		Timestamps: &Timestamps{Created: "today"},
+		meta:       meta{Owner: "root"},
+		Limits:     Limits{Max: 10},
+		Extra:      Limits{Max: 20},
+		Debug:      "verbose",
+	}
+
+	str, err := ini.Marshal(cfg)
+	expect(err).NoErr()
+	expect(str).ToBe(`name=app
+created=today
+owner=root
+max=20
+debug=verbose
+
+[Limits]
+max=10
+`)
+
+	result := Config{}
+	expect(ini.Unmarshal(str, &result)).NoErr()
+	expect(result.Common).ToBe(Common{Name: "app"})
+	expect(*result.Timestamps).ToBe(*cfg.Timestamps)
+	expect(result.meta).ToBe(cfg.meta)
+	expect(result.Limits).ToBe(cfg.Limits)
+	expect(result.Extra).ToBe(cfg.Extra)
+	expect(result.Debug).ToBe(cfg.Debug)
+
+	// fields of nil embedded structs are skipped
+	str, err = ini.Marshal(Config{})
+	expect(err).NoErr()
+	expect(strings.Contains(str, "created")).ToBe(false)
+}
+
+func TestMarshalEmbeddedConflicts(t *testing.T) {
+	expect := expect(t)
+
+	type A struct {
+		Value string `ini:"Value"`
+		Other string
+	}
+
+	type B struct {
+		Value string
+		Other string
+	}
+
+	type Config struct {
+		A
+		B
+	}
+
+	str, err := ini.Marshal(Config{
+		A: A{Value: "a", Other: "a"},
+		B: B{Value: "b", Other: "b"},
+	})
+	expect(err).NoErr()
+	// tagged field wins, ambiguous fields are ignored
+	expect(str).ToBe("Value=a\n")
+
+	type Named struct {
+		Common `ini:"common"`
+	}
+
+	str, err = ini.Marshal(Named{Common{Name: "x"}})
+	expect(err).NoErr()
+	expect(str).ToBe("[common]\nname=x\ndebug=false\n")
+}
+
+func TestMarshalPointerValues(t *testing.T) {
+	expect := expect(t)
+
+	type Config struct {
+		Port    *int           `ini:"port"`
+		Debug   *bool          `ini:"debug"`
+		Name    *string        `ini:"name"`
+		Timeout *time.Duration `ini:"timeout"`
+		Ratio   *float64       `ini:"ratio" default:"0.5"`
+		Level   *Level         `ini:"level"`
+	}
+
+	cfg := Config{}
+	expect(ini.Unmarshal("port=0\ndebug=false\ntimeout=1s\nlevel=info", &cfg)).NoErr()
+	expect(*cfg.Port).ToBe(0)
+	expect(*cfg.Debug).ToBe(false)
+	expect(cfg.Name == nil).ToBe(true)
+	expect(*cfg.Timeout).ToBe(time.Second)
+	expect(*cfg.Ratio).ToBe(0.5)
+	expect(*cfg.Level).ToBe(LevelInfo)
+
+	str, err := ini.Marshal(cfg)
+	expect(err).NoErr()
+	expect(str).ToBe("port=0\ndebug=false\ntimeout=1s\nratio=0.5\nlevel=info\n")
+
+	str, err = ini.Marshal(Config{})
+	expect(err).NoErr()
+	expect(str).ToBe("")
+
+	// pointers are left nil when the value is invalid
+	cfg = Config{}
+	err = ini.UnmarshalWithOptions("port=x\nname=app\nlevel=verbose", &cfg, ini.UnmarshalOptions{CollectErrors: true})
+	expect(err != nil).ToBe(true)
+	expect(cfg.Port == nil).ToBe(true)
+	expect(cfg.Level == nil).ToBe(true)
+	expect(*cfg.Name).ToBe("app")
+}
+
+func TestUnmarshalTypeErrors(t *testing.T) {
+	expect := expect(t)
+
+	type Db struct {
+		Port int `ini:"port"`
+	}
+
+	type Server struct {
+		Host    string  `ini:"host"`
+		Weights []uint8 `ini:"weights"`
+	}
+
+	type Config struct {
+		Ratio   float64         `ini:"ratio"`
+		Db      Db              `ini:"db"`
+		Servers []Server        `ini:"server"`
+		Limits  map[string]int  `ini:"limits"`
+		Name    string          `ini:"name,required"`
+		Level   Level           `ini:"level"`
+		Extra   map[string]bool `ini:"extra"`
+	}
+
+	data := `ratio=high
+level=verbose
+
+[db]
+port=abc
+
+[server.0]
+host=a
+weights=1,300
+
+[limits]
+max=10
+min=x
+`
+
+	cfg := Config{}
+	err := ini.Unmarshal(data, &cfg)
+
+	var typeErr *ini.UnmarshalTypeError
+	expect(errors.As(err, &typeErr)).ToBe(true)
+	expect(typeErr.Section).ToBe("")
+	expect(typeErr.Key).ToBe("ratio")
+	expect(typeErr.Value).ToBe("high")
+	expect(typeErr.Field).ToBe("Config.Ratio")
+	expect(err.Error()).ToBe(`ratio="high": expected float64`)
+
+	cfg = Config{Servers: []Server{{Host: "preset"}}}
+	err = ini.UnmarshalWithOptions(data, &cfg, ini.UnmarshalOptions{CollectErrors: true})
+
+	var errs ini.UnmarshalErrors
+	expect(errors.As(err, &errs)).ToBe(true)
+	expect(len(errs)).ToBe(6)
+	expect(err.Error()).ToBe(`6 unmarshal errors:
+ratio="high": expected float64
+[db] port="abc": expected int
+[server.0] weights="300": expected uint8: value out of range
+[limits] min="x": expected int
+required key 'name' is missing
+level="verbose": expected ini_test.Level: invalid level 'verbose'`)
+
+	fields := []string{}
+	for _, err := range errs {
+		if errors.As(err, &typeErr) {
+			fields = append(fields, typeErr.Field)
+		}
+	}
+	expect(fields).ToBe([]string{
+		"Config.Ratio",
+		"Config.Db.Port",
+		"Config.Servers[0].Weights[1]",
+		"Config.Limits[min]",
+		"Config.Level",
+	})
+
+	// valid values are still unmarshaled
+	expect(cfg.Limits["max"]).ToBe(10)
+	// lists with invalid elements keep the preset value
+	expect(cfg.Servers).ToBe([]Server{{Host: "preset"}})
+
+	type Ports struct {
+		Ports []int  `ini:"ports"`
+		Fixed [2]int `ini:"fixed"`
+	}
+
+	ports := Ports{Ports: []int{80}, Fixed: [2]int{1, 2}}
+	err = ini.UnmarshalWithOptions("ports=8080,x\nfixed=y", &ports, ini.UnmarshalOptions{CollectErrors: true})
+	expect(errors.As(err, &errs)).ToBe(true)
+	expect(len(errs)).ToBe(2)
+	expect(ports).ToBe(Ports{Ports: []int{80}, Fixed: [2]int{1, 2}})
+}
+
+func TestUnmarshalDisallowUnknown(t *testing.T) {
+	expect := expect(t)
+
+	type Replica struct {
+		Host string `ini:"host"`
+	}
+
+	type Db struct {
+		Port    int     `ini:"port"`
+		Replica Replica `ini:"replica"`
+	}
+
+	type Config struct {
+		Name    string               `ini:"name"`
+		Tags    []string             `ini:"tag,brackets"`
+		Db      Db                   `ini:"db"`
+		Labels  map[string]string    `ini:"labels"`
+		Servers []Replica            `ini:"server"`
+		Custom  CustomMarshalSection `ini:"custom"`
+	}
+
+	data := `name=app
+prot=8080
+tag[]=a
+
+[db]
+port=5432
+
+[db.replica]
+host=replica
+hots=typo
+
+[db.backup]
+host=backup
+
+[labels]
+env=prod
+
+[labels.nested]
+k=v
+
+[server.0]
+host=a
+
+[server.0.extra]
+k=v
+
+[custom]
+anything=goes
+
+[custom.sub]
+anything=goes
+
+[typo]
+k=v
+`
+
+	cfg := Config{}
+	err := ini.UnmarshalWithOptions(data, &cfg, ini.UnmarshalOptions{DisallowUnknown: true})
+	expect(err != nil).ToBe(true)
+	expect(err.Error()).ToBe(`6 unmarshal errors:
+unknown key 'prot'
+unknown key 'hots' in section [db.replica]
+unknown section [db.backup]
+unknown section [labels.nested]
+unknown section [server.0.extra]
+unknown section [typo]`)
+
+	var unknownErr *ini.UnknownKeyError
+	expect(errors.As(err, &unknownErr)).ToBe(true)
+	expect(unknownErr.Key).ToBe("prot")
+
+	// known values are unmarshaled regardless
+	expect(cfg.Db.Replica.Host).ToBe("replica")
+
+	// the decoder supports the option as well
+	dec := ini.NewDecoder(strings.NewReader("name=app\nprot=8080\n"))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&cfg)
+	expect(err != nil).ToBe(true)
+	expect(err.Error()).ToBe("unknown key 'prot'")
+
+	expect(ini.UnmarshalWithOptions("name=app\n[db]\nport=1\n", &cfg, ini.UnmarshalOptions{DisallowUnknown: true})).NoErr()
+}
+
+func TestUnmarshalCaseInsensitive(t *testing.T) {
+	expect := expect(t)
+
+	type Db struct {
+		Host string `ini:"host"`
+		Port int    `ini:"port"`
+	}
+
+	type Config struct {
+		Name string `ini:"name"`
+		Db   Db     `ini:"database"`
+	}
+
+	doc, err := ini.ParseWithOptions("NAME=app\n[Database]\nHost=localhost\nPort=5432\n", ini.ParseOptions{
+		DocOptions: ini.DocOptions{CaseInsensitiveKeys: true, CaseInsensitiveSections: true},
+	})
+	expect(err).NoErr()
+
+	cfg := Config{}
+	expect(ini.UnmarshalDocWithOptions(doc, &cfg, ini.UnmarshalOptions{DisallowUnknown: true})).NoErr()
+	expect(cfg).ToBe(Config{Name: "app", Db: Db{Host: "localhost", Port: 5432}})
+
+	// case sensitive by default
+	cfg = Config{}
+	expect(ini.Unmarshal("NAME=app\n[Database]\nHost=localhost\n", &cfg)).NoErr()
+	expect(cfg).ToBe(Config{})
+}
+
+func TestUnmarshalRepeatedSections(t *testing.T) {
+	expect := expect(t)
+
+	type Peer struct {
+		PublicKey  string   `ini:"PublicKey"`
+		AllowedIPs []string `ini:"AllowedIPs"`
+	}
+
+	type WireGuard struct {
+		Peers []Peer `ini:"Peer,repeat"`
+	}
+
+	doc, err := ini.ParseWithOptions(`[Peer]
+PublicKey=p1
+AllowedIPs=10.0.0.1/32
+
+[Peer]
+PublicKey=p2
+AllowedIPs=10.0.0.2/32,10.0.0.3/32
+`, ini.ParseOptions{DuplicateSections: ini.DuplicateSectionsSeparate})
+	expect(err).NoErr()
+
+	wg := WireGuard{}
+	expect(ini.UnmarshalDoc(doc, &wg)).NoErr()
+	expect(wg).ToBe(WireGuard{Peers: []Peer{
+		{PublicKey: "p1", AllowedIPs: []string{"10.0.0.1/32"}},
+		{PublicKey: "p2", AllowedIPs: []string{"10.0.0.2/32", "10.0.0.3/32"}},
+	}})
+}
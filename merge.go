@@ -0,0 +1,288 @@
+package ini
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrMergeConflict = errors.New("merge conflict")
+
+type MergeStrategy int
+
+const (
+	// Values of the merged document replace the existing values
+	MergeOverwrite MergeStrategy = iota
+	// Existing values are kept, only keys missing in the document are added
+	MergeKeepExisting
+	// Merge fails with an `ErrMergeConflict` error if a key has a different value in both documents
+	MergeErrorOnConflict
+)
+
+// Describes a key that has different values in both of the merged documents
+type MergeConflict struct {
+	// Full path of the section containing the key, empty for top level keys
+	Section string
+	Key     string
+	// Value in the document being merged into
+	Existing string
+	// Value in the document being merged
+	Incoming string
+}
+
+type MergeOptions struct {
+	Strategy MergeStrategy
+	// When set, it's called for every conflicting key instead of applying the Strategy.
+	// Returned value is used as the merged value, returning an error aborts the merge.
+	Resolve func(conflict MergeConflict) (string, error)
+}
+
+type conflictKey struct {
+	topLevel bool
+	section  string
+	key      string
+	// occurrence of the key, for keys that are repeated within a section
+	index int
+	// index of the section in the merged document, sections can be repeated as well
+	occurrence int
+}
+
+type merger struct {
+	doc      *IniDoc
+	opts     MergeOptions
+	source   string
+	resolved map[conflictKey]string
+	// set when merging with the conflicts resolved by a previous run
+	replay bool
+}
+
+// Combines the given documents into a new one. Documents are applied in order, values
+// of the later documents override the values of the earlier ones, key by key, in the
+// top level and in every section. Use `Source` on the result to find out which file
+// the effective value of a key came from.
+//
+// The result uses the options of the first non-nil document.
+func Merge(docs ...*IniDoc) *IniDoc {
+	var result *IniDoc
+	for _, doc := range docs {
+		if doc != nil && result == nil {
+			result = NewDocWithOptions(doc.opts)
+		}
+	}
+	if result == nil {
+		return NewDoc()
+	}
+
+	for _, doc := range docs {
+		if doc != nil {
+			result.Merge(doc, MergeOptions{})
+		}
+	}
+	return result
+}
+
+// Merges the [other] document into this one. Top level keys and keys of sections and
+// subsections are merged one by one, keys that exist only in one of the documents are
+// always kept. Conflicting values are handled according to the given options.
+//
+// Repeated keys (see `GetAll`) are merged occurrence by occurrence, the n-th value of
+// a key is merged with the n-th value of that key in this document.
+//
+// Field and section comments follow the values: comments of the [other] document are
+// used when it's values are, or when the existing comment is empty. Standalone comments
+// and white lines are carried over together with the keys they precede.
+//
+// All conflicts are resolved before the document is modified, if an error is returned
+// the document is left unchanged. Merging a nil document is a no-op.
+func (d *IniDoc) Merge(other *IniDoc, opts MergeOptions) error {
+	if other == nil {
+		return nil
+	}
+
+	m := merger{
+		opts:     opts,
+		source:   other.source,
+		resolved: make(map[conflictKey]string),
+	}
+
+	// dry run on a copy of the document first, all conflicts are resolved in it,
+	// so that nothing is changed on failure
+	err := m.merge(d.Clone(), other)
+	if err != nil {
+		return err
+	}
+
+	// conflicts can't fail at this point, the resolutions of the dry run are reused
+	m.replay = true
+	return m.merge(d, other)
+}
+
+// Merges the [other] document into [d], sections are merged one at a time, so that
+// repeated sections of [other] are merged with the result of the previous occurrence
+func (m *merger) merge(d *IniDoc, other *IniDoc) error {
+	m.doc = d
+
+	ck := conflictKey{topLevel: true}
+	err := m.resolveLines(d.lines, other.lines, ck)
+	if err != nil {
+		return err
+	}
+	d.lines = m.mergeLines(d.lines, other.lines, ck)
+
+	for idx, otherSection := range other.sections {
+		section := d.findSection(otherSection.name)
+		if section == nil {
+			section = d.Section(otherSection.name)
+		}
+
+		ck := conflictKey{section: otherSection.name, occurrence: idx}
+		err := m.resolveLines(section.lines, otherSection.lines, ck)
+		if err != nil {
+			return err
+		}
+
+		if otherSection.comment != "" && (section.comment == "" || m.prefersIncoming()) {
+			section.comment = otherSection.comment
+		}
+		section.lines = m.mergeLines(section.lines, otherSection.lines, ck)
+	}
+
+	return nil
+}
+
+func (m *merger) prefersIncoming() bool {
+	return m.opts.Resolve == nil && m.opts.Strategy == MergeOverwrite
+}
+
+// Decides the value of every conflicting key in the given lines
+func (m *merger) resolveLines(dst []iniLine, src []iniLine, ck conflictKey) error {
+	if m.replay {
+		return nil
+	}
+
+	occurrences := make(map[string]int)
+	for _, line := range src {
+		if line.lineType != lineTypeKv {
+			continue
+		}
+
+		index := occurrences[m.doc.keyID(line.key)]
+		occurrences[m.doc.keyID(line.key)]++
+
+		existing := m.doc.findLine(dst, line.key, index)
+		if existing == nil || existing.value == line.value {
+			continue
+		}
+
+		ck.key = line.key
+		ck.index = index
+		conflict := MergeConflict{
+			Section:  ck.section,
+			Key:      line.key,
+			Existing: existing.value,
+			Incoming: line.value,
+		}
+
+		if m.opts.Resolve != nil {
+			value, err := m.opts.Resolve(conflict)
+			if err != nil {
+				return err
+			}
+			m.resolved[ck] = value
+			continue
+		}
+
+		switch m.opts.Strategy {
+		case MergeOverwrite:
+			m.resolved[ck] = line.value
+		case MergeKeepExisting:
+			m.resolved[ck] = existing.value
+		case MergeErrorOnConflict:
+			if ck.topLevel {
+				return fmt.Errorf("%w: key '%s' (%q != %q)", ErrMergeConflict, line.key, existing.value, line.value)
+			}
+			return fmt.Errorf("%w: key '%s' in section [%s] (%q != %q)", ErrMergeConflict, line.key, ck.section, existing.value, line.value)
+		default:
+			return fmt.Errorf("invalid merge strategy: %d", m.opts.Strategy)
+		}
+	}
+
+	return nil
+}
+
+// Merges the [src] lines into [dst]. Comments and white lines are carried over only
+// together with the keys they precede, unless [dst] is empty, in which case all lines
+// are copied.
+func (m *merger) mergeLines(dst []iniLine, src []iniLine, ck conflictKey) []iniLine {
+	copyAll := len(dst) == 0
+	pending := make([]iniLine, 0, 4)
+	occurrences := make(map[string]int)
+
+	for _, line := range src {
+		if line.source == "" {
+			line.source = m.source
+		}
+
+		if line.lineType != lineTypeKv {
+			pending = append(pending, line)
+			continue
+		}
+
+		ck.index = occurrences[m.doc.keyID(line.key)]
+		occurrences[m.doc.keyID(line.key)]++
+
+		existing := m.doc.findLine(dst, line.key, ck.index)
+		if existing != nil {
+			m.mergeLine(existing, line, ck)
+		} else {
+			dst = append(dst, pending...)
+			dst = append(dst, line)
+		}
+		pending = pending[:0]
+	}
+
+	if copyAll {
+		dst = append(dst, pending...)
+	}
+
+	return dst
+}
+
+func (m *merger) mergeLine(existing *iniLine, incoming iniLine, ck conflictKey) {
+	value := incoming.value
+	if existing.value != incoming.value {
+		ck.key = incoming.key
+		value = m.resolved[ck]
+	}
+
+	useIncoming := value == incoming.value && (existing.value != incoming.value || m.prefersIncoming())
+
+	if useIncoming {
+		existing.source = incoming.source
+		if incoming.comment != "" {
+			existing.comment = incoming.comment
+		}
+	} else if value != existing.value {
+		// custom value returned by the resolver
+		existing.source = ""
+	}
+
+	if existing.comment == "" {
+		existing.comment = incoming.comment
+	}
+
+	existing.value = value
+}
+
+// Returns the [index]-th key-value line with the given key, keys are compared according
+// to the options of the document
+func (d *IniDoc) findLine(lines []iniLine, key string, index int) *iniLine {
+	for idx := range lines {
+		if lines[idx].lineType == lineTypeKv && d.keysEqual(lines[idx].key, key) {
+			if index == 0 {
+				return &lines[idx]
+			}
+			index--
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package ini_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ncpa0cpl/ini"
+)
+
+func TestMergeLayers(t *testing.T) {
+	expect := expect(t)
+
+	system := ini.Parse(`; system defaults
+level=info
+color=false
+
+[db]
+host=localhost
+port=5432
+`)
+	user := ini.Parse(`color=true
+
+[db]
+port=6543 ; custom port
+`)
+	project := ini.Parse(`[db]
+name=project
+
+[db.pool]
+size=4
+`)
+
+	doc := ini.Merge(system, user, project)
+
+	expect(doc.ToString()).ToBe(`; system defaults
+level=info
+color=true
+
+[db]
+host=localhost
+port=6543 ; custom port
+name=project
+
+[db.pool]
+size=4
+`)
+
+	// inputs are not modified
+	expect(system.Get("color")).ToBe("false")
+	expect(system.Section("db").Keys()).ToBe([]string{"host", "port"})
+}
+
+func TestLoadLayered(t *testing.T) {
+	expect := expect(t)
+
+	fsys := fstest.MapFS{
+		"etc/app.ini":  &fstest.MapFile{Data: []byte("level=info\ncolor=false\n[db]\nport=5432\n")},
+		"home/app.ini": &fstest.MapFile{Data: []byte("color=true\n")},
+		"proj/app.ini": &fstest.MapFile{Data: []byte("[db]\nport=6543\n")},
+	}
+
+	doc, err := ini.LoadLayeredFS(fsys, "etc/app.ini", "home/app.ini", "missing/app.ini", "proj/app.ini")
+	expect(err).NoErr()
+
+	expect(doc.Get("level")).ToBe("info")
+	expect(doc.Source("level")).ToBe("etc/app.ini")
+	expect(doc.Get("color")).ToBe("true")
+	expect(doc.Source("color")).ToBe("home/app.ini")
+	expect(doc.Section("db").Get("port")).ToBe("6543")
+	expect(doc.Section("db").Source("port")).ToBe("proj/app.ini")
+	expect(doc.Source("missing")).ToBe("")
+
+	doc.Set("color", "false")
+	expect(doc.Source("color")).ToBe("")
+
+	doc, err = ini.LoadLayered("./test.ini", "./does-not-exist.ini", "./test2.ini")
+	expect(err).NoErr()
+	expect(doc.Get("c")).ToBe("d")
+	expect(doc.Source("c")).ToBe("./test.ini")
+	expect(doc.Section("s2").Source("k2")).ToBe("./test2.ini")
+}
+
+func TestDocMergeStrategies(t *testing.T) {
+	expect := expect(t)
+
+	base := `top=1
+[s]
+a=1 ; base comment
+b=1
+`
+	other := ini.Parse(`top=2
+added=yes
+[s]
+a=2 ; other comment
+c=2
+
+; new section
+[s.sub]
+k=v
+`)
+
+	doc := ini.Parse(base)
+	expect(doc.Merge(nil, ini.MergeOptions{})).NoErr()
+	expect(doc.ToString()).ToBe(ini.Parse(base).ToString())
+
+	expect(doc.Merge(other, ini.MergeOptions{Strategy: ini.MergeOverwrite})).NoErr()
+	expect(doc.ToString()).ToBe(`top=2
+added=yes
+
+[s]
+a=2 ; other comment
+b=1
+c=2
+
+; new section
+[s.sub]
+k=v
+`)
+
+	doc = ini.Parse(base)
+	expect(doc.Merge(other, ini.MergeOptions{Strategy: ini.MergeKeepExisting})).NoErr()
+	expect(doc.Get("top")).ToBe("1")
+	expect(doc.Get("added")).ToBe("yes")
+	expect(doc.Section("s").Get("a")).ToBe("1")
+	expect(doc.Section("s").GetComment("a")).ToBe("base comment")
+	expect(doc.Section("s").Get("c")).ToBe("2")
+	expect(doc.Section("s.sub").Get("k")).ToBe("v")
+
+	doc = ini.Parse(base)
+	err := doc.Merge(other, ini.MergeOptions{Strategy: ini.MergeErrorOnConflict})
+	expect(errors.Is(err, ini.ErrMergeConflict)).ToBe(true)
+	expect(err.Error()).ToBe(`merge conflict: key 'top' ("1" != "2")`)
+	// document is left untouched on error
+	expect(doc.ToString()).ToBe(ini.Parse(base).ToString())
+
+	conflicts := []ini.MergeConflict{}
+	doc = ini.Parse(base)
+	err = doc.Merge(other, ini.MergeOptions{
+		Resolve: func(c ini.MergeConflict) (string, error) {
+			conflicts = append(conflicts, c)
+			return c.Existing + c.Incoming, nil
+		},
+	})
+	expect(err).NoErr()
+	expect(conflicts).ToBe([]ini.MergeConflict{
+		{Section: "", Key: "top", Existing: "1", Incoming: "2"},
+		{Section: "s", Key: "a", Existing: "1", Incoming: "2"},
+	})
+	expect(doc.Get("top")).ToBe("12")
+	expect(doc.Section("s").Get("a")).ToBe("12")
+
+	resolveErr := errors.New("refusing to merge")
+	doc = ini.Parse(base)
+	err = doc.Merge(other, ini.MergeOptions{
+		Resolve: func(c ini.MergeConflict) (string, error) {
+			if c.Section == "s" {
+				return "", resolveErr
+			}
+			return c.Incoming, nil
+		},
+	})
+	expect(err).ToBe(resolveErr)
+	expect(doc.ToString()).ToBe(ini.Parse(base).ToString())
+}
+
+func TestMergeCaseInsensitive(t *testing.T) {
+	expect := expect(t)
+
+	opts := ini.ParseOptions{DocOptions: ini.DocOptions{CaseInsensitiveKeys: true, CaseInsensitiveSections: true}}
+
+	base, err := ini.ParseWithOptions("Level=info\n\n[DB]\nPort=5432\n", opts)
+	expect(err).NoErr()
+	override, err := ini.ParseWithOptions("level=debug\n\n[db]\nport=6543\n", opts)
+	expect(err).NoErr()
+
+	doc := ini.Merge(nil, base, override)
+	expect(doc.Options()).ToBe(opts.DocOptions)
+	expect(doc.ToString()).ToBe("Level=debug\n\n[DB]\nPort=6543\n")
+
+	expect(ini.Merge().ToString()).ToBe("")
+	expect(ini.Merge(nil).ToString()).ToBe("")
+}
+
+func TestMergeRepeatedKeys(t *testing.T) {
+	expect := expect(t)
+
+	opts := ini.ParseOptions{DuplicateKeys: ini.DuplicateKeysKeepAll}
+
+	base, err := ini.ParseWithOptions("host=a\nhost=b\ntag[]=x\n", opts)
+	expect(err).NoErr()
+	override, err := ini.ParseWithOptions("host=c\ntag[]=x\ntag[]=y\n", opts)
+	expect(err).NoErr()
+
+	// the n-th value is merged with the n-th value of the same key
+	expect(base.Merge(override, ini.MergeOptions{})).NoErr()
+	expect(base.GetAll("host")).ToBe([]string{"c", "b"})
+	expect(base.GetAll("tag[]")).ToBe([]string{"x", "y"})
+
+	err = base.Merge(ini.Parse("host=c\nhost=d\n"), ini.MergeOptions{Strategy: ini.MergeErrorOnConflict})
+	expect(errors.Is(err, ini.ErrMergeConflict)).ToBe(true)
+	expect(base.GetAll("host")).ToBe([]string{"c", "b"})
+}
+
+func TestMergeRepeatedSections(t *testing.T) {
+	expect := expect(t)
+
+	other, err := ini.ParseWithOptions("[s]\na=1\n\n[s]\na=2\nb=3\n", ini.ParseOptions{
+		DuplicateSections: ini.DuplicateSectionsSeparate,
+	})
+	expect(err).NoErr()
+
+	// later occurrences are merged with the result of the earlier ones
+	doc := ini.NewDoc()
+	expect(doc.Merge(other, ini.MergeOptions{})).NoErr()
+	expect(doc.ToString()).ToBe("[s]\na=2\n\nb=3\n")
+
+	doc = ini.NewDoc()
+	expect(doc.Merge(other, ini.MergeOptions{Strategy: ini.MergeKeepExisting})).NoErr()
+	expect(doc.ToString()).ToBe("[s]\na=1\n\nb=3\n")
+
+	doc = ini.Parse("top=1\n")
+	err = doc.Merge(other, ini.MergeOptions{Strategy: ini.MergeErrorOnConflict})
+	expect(errors.Is(err, ini.ErrMergeConflict)).ToBe(true)
+	expect(doc.ToString()).ToBe("top=1\n")
+
+	// the resolver is called once for every conflict
+	calls := 0
+	doc = ini.NewDoc()
+	expect(doc.Merge(other, ini.MergeOptions{
+		Resolve: func(c ini.MergeConflict) (string, error) {
+			calls++
+			return c.Existing + c.Incoming, nil
+		},
+	})).NoErr()
+	expect(calls).ToBe(1)
+	expect(doc.Section("s").Get("a")).ToBe("12")
+}
@@ -2,6 +2,7 @@ package ini
 
 import (
 	"strings"
+	"time"
 )
 
 const (
@@ -10,167 +11,424 @@ const (
 	parseStepFieldComment
 	parseStepSection
 	parseStepKey
+	// whitespace delimiter was found, possibly followed by another delimiter (`key = value`)
+	parseStepDelimiter
 	parseStepValue
 )
 
 type docOrSection interface {
 	Del(key string)
 	Get(key string) string
+	Has(key string) bool
+	HasSection(name string) bool
 	GetBool(key string) (bool, error)
+	GetDuration(key string) (time.Duration, error)
 	GetFloat(key string) (float64, error)
 	GetInt(key string) (int64, error)
+	GetTime(key string, layout string) (time.Time, error)
 	GetUint(key string) (uint64, error)
 	Set(key string, value string)
 	SetBool(key string, value bool)
+	SetDuration(key string, value time.Duration)
 	SetFieldComment(fieldKey string, value string)
 	SetFloat(key string, value float64)
 	SetInt(key string, value int64)
+	SetTime(key string, value time.Time, layout string)
 	SetUint(key string, value uint64)
 	AddComment(value string)
 	AddHashComment(value string)
 	AddWhiteLine()
 	Section(name string) *IniSection
 	ToString() string
-	addParsedSection(name string) *IniSection
+	GetAll(key string) []string
+	AddValue(key, value string)
+	addParsedSection(name string, appendNew bool) *IniSection
+	setLastFieldComment(fieldKey string, value string)
 }
 
-func Parse(content string) *IniDoc {
-	var key string
+type ParseOptions struct {
+	// When enabled, malformed lines are reported as a `ParseErrors` error instead of
+	// being silently skipped.
+	Strict bool
+	// How keys repeated within the same section are handled, keys ending with `[]`
+	// are always kept
+	DuplicateKeys DuplicateKeyPolicy
+	// How sections repeated within the document are handled
+	DuplicateSections DuplicateSectionPolicy
+	// Characters accepted as key-value delimiters, `=` if empty (e.x. `=:` accepts both
+	// `key=value` and `key: value`). A space or a tab in the set allows `key value`
+	// lines, whitespace around other delimiters is ignored in that case.
+	Delimiters string
+	// Options of the parsed document
+	DocOptions
+}
+
+type DuplicateKeyPolicy int
+
+const (
+	// The last value replaces the earlier ones
+	DuplicateKeysLastWins DuplicateKeyPolicy = iota
+	// The first value is kept, later ones are ignored
+	DuplicateKeysFirstWins
+	// The first value is kept and every repeated key is reported as a `ParseError`, even
+	// if the `Strict` option is not enabled
+	DuplicateKeysError
+	// Every occurrence is kept, use `GetAll` to retrieve all values
+	DuplicateKeysKeepAll
+)
+
+type DuplicateSectionPolicy int
+
+const (
+	// Keys of all occurrences are merged into a single section
+	DuplicateSectionsMerge DuplicateSectionPolicy = iota
+	// Every occurrence is kept as a separate section, use `IniDoc.Sections` to retrieve
+	// all of them
+	DuplicateSectionsSeparate
+)
+
+type parser struct {
+	opts ParseOptions
+	errs ParseErrors
+
+	doc        *IniDoc
+	currentDoc docOrSection
+	// names of the section headers found so far
+	parsedSections map[string]bool
+
+	step        int
+	key         string
+	skipComment bool
+	escaped     bool
+	commentType rune
+	buff        []rune
+	prev        rune
+
+	// position of the character currently being processed
+	line   int
+	column int
+	// position where the currently parsed line started
+	startLine   int
+	startColumn int
+}
+
+func newParser(opts ParseOptions) *parser {
+	doc := NewDocWithOptions(opts.DocOptions)
+
+	return &parser{
+		opts:           opts,
+		doc:            doc,
+		currentDoc:     doc,
+		parsedSections: make(map[string]bool),
+		step:           parseStepLookup,
+		commentType:    ';',
+		buff:           make([]rune, 0, 16),
+		prev:           '\n',
+	}
+}
+
+func (p *parser) addError(reason ParseErrorReason, text string) {
+	p.errs = append(p.errs, &ParseError{
+		Line:   p.startLine,
+		Column: p.startColumn,
+		Text:   text,
+		Reason: reason,
+	})
+}
+
+// Reports a line that has a key but no delimiter, lines containing only whitespace
+// characters are ignored
+func (p *parser) addKeyOnlyError() {
+	text := strings.Trim(string(p.buff), " \t\r")
+	if text != "" {
+		p.addError(ParseErrMissingDelimiter, text)
+	}
+}
+
+func (p *parser) isDelimiter(char rune) bool {
+	if p.opts.Delimiters == "" {
+		return char == '='
+	}
+	return strings.ContainsRune(p.opts.Delimiters, char)
+}
+
+func isWhitespace(char rune) bool {
+	return char == ' ' || char == '\t'
+}
 
-	step := parseStepLookup
-	escaped := false
-	commentType := ';'
-	buff := make([]rune, 0, 16)
+func (p *parser) resetBuff() {
+	p.buff = make([]rune, 0, 16)
+}
+
+func (p *parser) setValue() {
+	if !isKeyValid(p.key) {
+		p.addError(ParseErrInvalidKey, p.key)
+	}
 
-	doc := NewDoc()
+	value := strings.Trim(string(p.buff), " ")
+	p.skipComment = false
 
-	var currentDoc docOrSection
-	currentDoc = doc
+	if isListKey(p.key) {
+		// keys with brackets are never overridden (`host[]=a`, `host[]=b`)
+		p.currentDoc.AddValue(p.key, value)
+		return
+	}
 
-	for idx, char := range content {
-		if char == '\\' && !escaped {
-			escaped = true
-			continue
+	switch p.opts.DuplicateKeys {
+	case DuplicateKeysFirstWins, DuplicateKeysError:
+		if p.currentDoc.Has(p.key) {
+			if p.opts.DuplicateKeys == DuplicateKeysError {
+				p.addError(ParseErrDuplicateKey, p.key)
+			}
+			p.skipComment = true
+			return
 		}
+		p.currentDoc.Set(p.key, value)
+	case DuplicateKeysKeepAll:
+		p.currentDoc.AddValue(p.key, value)
+	default:
+		p.currentDoc.Set(p.key, value)
+	}
+}
 
-		switch step {
-		case parseStepLookup:
-			if !escaped {
-				switch char {
-				case '[':
-					step = parseStepSection
-					continue
-				case ';':
-					step = parseStepComment
-					commentType = ';'
-					continue
-				case '#':
-					step = parseStepComment
-					commentType = '#'
-					continue
-				case '\n':
-					if idx == 0 || content[idx-1] == '\n' {
-						currentDoc.AddWhiteLine()
-					}
-					continue
-				case ' ':
-					continue
-				}
-			} else {
-				escaped = false
+// Sets the comment of the key-value pair that was just parsed
+func (p *parser) setFieldComment() {
+	if !p.skipComment {
+		p.currentDoc.setLastFieldComment(p.key, strings.Trim(string(p.buff), " "))
+	}
+}
+
+// Feeds the next character of the document to the parser
+func (p *parser) feed(char rune) {
+	if p.prev == '\n' {
+		p.line++
+		p.column = 1
+	} else {
+		p.column++
+	}
+
+	p.parseChar(char)
+	p.prev = char
+}
+
+func (p *parser) parseChar(char rune) {
+	if char == '\\' && !p.escaped {
+		if p.step == parseStepLookup {
+			p.startLine, p.startColumn = p.line, p.column
+		}
+		p.escaped = true
+		return
+	}
+
+	switch p.step {
+	case parseStepLookup:
+		p.startLine, p.startColumn = p.line, p.column
+		if !p.escaped {
+			if p.isDelimiter(char) && !isWhitespace(char) {
+				p.key = ""
+				p.step = parseStepValue
+				return
 			}
-			step = parseStepKey
-			buff = append(buff, char)
-		case parseStepKey:
 			switch char {
-			case '=':
-				if !escaped {
-					key = strings.Trim(string(buff), " ")
-					buff = make([]rune, 0, 16)
-					step = parseStepValue
-				} else {
-					buff = append(buff, char)
-				}
+			case '[':
+				p.step = parseStepSection
+				return
+			case ';':
+				p.step = parseStepComment
+				p.commentType = ';'
+				return
+			case '#':
+				p.step = parseStepComment
+				p.commentType = '#'
+				return
 			case '\n':
-				buff = make([]rune, 0, 16)
-				step = parseStepLookup
-			default:
-				buff = append(buff, char)
-			}
-			escaped = false
-		case parseStepValue:
-			if !escaped {
-				switch char {
-				case ';', '#':
-					currentDoc.Set(key, strings.Trim(string(buff), " "))
-					buff = make([]rune, 0, 16)
-					step = parseStepFieldComment
-					continue
-				case '\n':
-					currentDoc.Set(key, strings.Trim(string(buff), " "))
-					buff = make([]rune, 0, 16)
-					key = ""
-					step = parseStepLookup
-					continue
+				if p.prev == '\n' {
+					p.currentDoc.AddWhiteLine()
 				}
+				return
+			case ' ':
+				return
+			}
+		} else {
+			p.escaped = false
+		}
+		p.step = parseStepKey
+		p.buff = append(p.buff, char)
+	case parseStepKey:
+		switch {
+		case char == '\n':
+			p.addKeyOnlyError()
+			p.resetBuff()
+			p.step = parseStepLookup
+		case p.isDelimiter(char) && !p.escaped:
+			p.key = strings.Trim(string(p.buff), " ")
+			p.resetBuff()
+			if isWhitespace(char) {
+				p.step = parseStepDelimiter
 			} else {
-				escaped = false
-				if char == 'N' || char == 'n' {
-					buff = append(buff, '\n')
-					continue
-				}
+				p.step = parseStepValue
+			}
+		default:
+			p.buff = append(p.buff, char)
+		}
+		p.escaped = false
+	case parseStepDelimiter:
+		if !p.escaped {
+			if isWhitespace(char) {
+				return
+			}
+			if p.isDelimiter(char) {
+				p.step = parseStepValue
+				return
 			}
-			buff = append(buff, char)
-		case parseStepSection:
+		}
+		// first character of the value
+		p.step = parseStepValue
+		p.parseChar(char)
+	case parseStepValue:
+		if !p.escaped {
 			switch char {
-			case ']':
-				if !escaped {
-					currentDoc = doc.addParsedSection(strings.Trim(string(buff), " "))
-					buff = make([]rune, 0, 16)
-					step = parseStepLookup
-				} else {
-					buff = append(buff, char)
-				}
+			case ';', '#':
+				p.setValue()
+				p.resetBuff()
+				p.step = parseStepFieldComment
+				return
 			case '\n':
-				buff = make([]rune, 0, 16)
-				step = parseStepLookup
-			default:
-				buff = append(buff, char)
+				p.setValue()
+				p.resetBuff()
+				p.key = ""
+				p.step = parseStepLookup
+				return
 			}
-			escaped = false
-		case parseStepFieldComment:
-			if char == '\n' && !escaped {
-				currentDoc.SetFieldComment(key, strings.Trim(string(buff), " "))
-				buff = make([]rune, 0, 16)
-				key = ""
-				step = parseStepLookup
-			} else {
-				escaped = false
-				buff = append(buff, char)
+		} else {
+			p.escaped = false
+			if char == 'N' || char == 'n' {
+				p.buff = append(p.buff, '\n')
+				return
 			}
-		case parseStepComment:
-			if char == '\n' && !escaped {
-				if commentType == ';' {
-					currentDoc.AddComment(strings.Trim(string(buff), " "))
-				} else {
-					currentDoc.AddHashComment(strings.Trim(string(buff), " "))
+		}
+		p.buff = append(p.buff, char)
+	case parseStepSection:
+		switch char {
+		case ']':
+			if !p.escaped {
+				name := strings.Trim(string(p.buff), " ")
+				if name == "" {
+					p.addError(ParseErrInvalidSection, "[]")
 				}
-				buff = make([]rune, 0, 16)
-				step = parseStepLookup
+				p.addSection(name)
+				p.resetBuff()
+				p.step = parseStepLookup
 			} else {
-				escaped = false
-				buff = append(buff, char)
+				p.buff = append(p.buff, char)
 			}
+		case '\n':
+			p.addError(ParseErrUnterminatedSection, "["+string(p.buff))
+			p.resetBuff()
+			p.step = parseStepLookup
+		default:
+			p.buff = append(p.buff, char)
+		}
+		p.escaped = false
+	case parseStepFieldComment:
+		if char == '\n' && !p.escaped {
+			p.setFieldComment()
+			p.resetBuff()
+			p.key = ""
+			p.step = parseStepLookup
+		} else {
+			p.escaped = false
+			p.buff = append(p.buff, char)
+		}
+	case parseStepComment:
+		if char == '\n' && !p.escaped {
+			p.addComment()
+			p.resetBuff()
+			p.step = parseStepLookup
+		} else {
+			p.escaped = false
+			p.buff = append(p.buff, char)
 		}
 	}
+}
 
-	if key != "" && len(buff) > 0 {
-		if step == parseStepFieldComment {
-			currentDoc.SetFieldComment(key, strings.Trim(string(buff), " "))
-		} else {
-			currentDoc.Set(key, strings.Trim(string(buff), " "))
+func (p *parser) addSection(name string) {
+	id := p.doc.sectionID(name)
+	appendNew := p.opts.DuplicateSections == DuplicateSectionsSeparate && p.parsedSections[id]
+	p.parsedSections[id] = true
+	p.currentDoc = p.doc.addParsedSection(name, appendNew)
+}
+
+func (p *parser) addComment() {
+	if p.commentType == ';' {
+		p.currentDoc.AddComment(strings.Trim(string(p.buff), " "))
+	} else {
+		p.currentDoc.AddHashComment(strings.Trim(string(p.buff), " "))
+	}
+}
+
+// Flushes the last, not terminated line of the document and returns the parsed result
+func (p *parser) finish() (*IniDoc, error) {
+	if p.escaped {
+		p.addError(ParseErrDanglingEscape, "\\")
+	}
+
+	switch p.step {
+	case parseStepValue, parseStepDelimiter:
+		p.setValue()
+	case parseStepFieldComment:
+		p.setFieldComment()
+	case parseStepComment:
+		p.addComment()
+	case parseStepKey:
+		p.addKeyOnlyError()
+	case parseStepSection:
+		p.addError(ParseErrUnterminatedSection, "["+string(p.buff))
+	}
+
+	p.step = parseStepLookup
+	p.resetBuff()
+
+	if p.opts.Strict && len(p.errs) > 0 {
+		return p.doc, p.errs
+	}
+
+	if p.opts.DuplicateKeys == DuplicateKeysError {
+		var errs ParseErrors
+		for _, err := range p.errs {
+			if err.Reason == ParseErrDuplicateKey {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return p.doc, errs
 		}
 	}
 
+	return p.doc, nil
+}
+
+// Parses the given ini document. Malformed lines are skipped, use `ParseStrict` if
+// those should be reported instead.
+func Parse(content string) *IniDoc {
+	doc, _ := ParseWithOptions(content, ParseOptions{})
 	return doc
 }
+
+// Parses the given ini document and returns a `ParseErrors` error listing every
+// malformed line found in it (unterminated section headers, keys without a value, etc.).
+// The returned document contains everything that could be parsed, even if an error
+// is returned.
+func ParseStrict(content string) (*IniDoc, error) {
+	return ParseWithOptions(content, ParseOptions{Strict: true})
+}
+
+func ParseWithOptions(content string, opts ParseOptions) (*IniDoc, error) {
+	p := newParser(opts)
+
+	for _, char := range content {
+		p.feed(char)
+	}
+
+	return p.finish()
+}
@@ -0,0 +1,174 @@
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// The key of a `delete` or `rename` operation does not exist
+	ErrPatchKeyNotFound = errors.New("key does not exist")
+	// The target key of a `rename` operation already exists
+	ErrPatchKeyExists  = errors.New("key already exists")
+	ErrPatchInvalidKey = errors.New("invalid key")
+	ErrPatchUnknownOp  = errors.New("unknown operation")
+)
+
+type PatchOpType string
+
+const (
+	// Sets the value of the key, key and section are created if those don't exist
+	PatchSet PatchOpType = "set"
+	// Removes the key
+	PatchDelete PatchOpType = "delete"
+	// Moves the value (and comment) of the key to the key given in `To`
+	PatchRename PatchOpType = "rename"
+)
+
+// Single operation of a `Patch`. Keys are addressed by paths made of the full section
+// path and the key, joined with a dot (e.x. `server.tls.cert` is the key `cert` in the
+// section `[server.tls]`), paths without a dot address top level keys. Dots within
+// keys are escaped with a backslash (e.x. `log\.level` is the top level key `log.level`).
+type PatchOp struct {
+	Op    PatchOpType `json:"op"`
+	Path  string      `json:"path"`
+	Value string      `json:"value,omitempty"`
+	To    string      `json:"to,omitempty"`
+}
+
+// List of operations applied to a document, can be serialized as JSON
+type Patch []PatchOp
+
+// Applies all operations of the patch, in order. Either all operations succeed or the
+// document is left unchanged and an error describing the first failed operation is
+// returned.
+func (d *IniDoc) Apply(patch Patch) error {
+	// dry run on a copy of the document first, so that nothing is changed on failure
+	work := d.Clone()
+	for idx, op := range patch {
+		err := work.applyOp(op)
+		if err != nil {
+			return fmt.Errorf("patch operation %d (%s '%s'): %w", idx, op.Op, op.Path, err)
+		}
+	}
+
+	// operations can't fail at this point, since all of them succeeded in the dry run
+	for _, op := range patch {
+		d.applyOp(op)
+	}
+
+	return nil
+}
+
+func (d *IniDoc) applyOp(op PatchOp) error {
+	sectionName, key := splitKeyPath(op.Path)
+
+	switch op.Op {
+	case PatchSet:
+		if !isKeyValid(key) {
+			return fmt.Errorf("%w: '%s'", ErrPatchInvalidKey, key)
+		}
+		d.keyContainer(sectionName, true).Set(key, op.Value)
+	case PatchDelete:
+		container := d.keyContainer(sectionName, false)
+		if container == nil || container.getField(key) == nil {
+			return ErrPatchKeyNotFound
+		}
+		container.Del(key)
+	case PatchRename:
+		container := d.keyContainer(sectionName, false)
+		if container == nil || container.getField(key) == nil {
+			return ErrPatchKeyNotFound
+		}
+
+		toSectionName, toKey := splitKeyPath(op.To)
+		if !isKeyValid(toKey) {
+			return fmt.Errorf("%w: '%s'", ErrPatchInvalidKey, toKey)
+		}
+
+		toContainer := d.keyContainer(toSectionName, true)
+		if toContainer.getField(toKey) != nil {
+			return fmt.Errorf("%w: '%s'", ErrPatchKeyExists, op.To)
+		}
+
+		if toSectionName == sectionName {
+			container.getField(key).key = toKey
+			return nil
+		}
+
+		line := *container.getField(key)
+		container.Del(key)
+		toContainer.Set(toKey, line.value)
+		toContainer.SetFieldComment(toKey, line.comment)
+	default:
+		return ErrPatchUnknownOp
+	}
+
+	return nil
+}
+
+type keyContainer interface {
+	getField(key string) *iniLine
+	Set(key, value string)
+	Del(key string)
+	SetFieldComment(fieldKey string, value string)
+}
+
+// Returns the document itself for the empty section name or the named section,
+// the section is created if it does not exist and [create] is true
+func (d *IniDoc) keyContainer(sectionName string, create bool) keyContainer {
+	if sectionName == "" {
+		return d
+	}
+
+	section := d.findSection(sectionName)
+	if section == nil {
+		if !create {
+			return nil
+		}
+		section = d.Section(sectionName)
+	}
+	return section
+}
+
+// Splits the `section.key` path into the section path and key, on the last dot that
+// is not escaped with a backslash
+func splitKeyPath(path string) (string, string) {
+	lastDotIdx := -1
+	escaped := false
+	for idx := 0; idx < len(path); idx++ {
+		switch {
+		case escaped:
+			escaped = false
+		case path[idx] == '\\':
+			escaped = true
+		case path[idx] == '.':
+			lastDotIdx = idx
+		}
+	}
+
+	if lastDotIdx == -1 {
+		return "", unescapeKeyPath(path)
+	}
+	return unescapeKeyPath(path[:lastDotIdx]), unescapeKeyPath(path[lastDotIdx+1:])
+}
+
+// Removes the backslashes escaping dots and backslashes in a path
+func unescapeKeyPath(path string) string {
+	if !strings.Contains(path, "\\") {
+		return path
+	}
+
+	var sb strings.Builder
+	escaped := false
+	for _, char := range path {
+		if char == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		sb.WriteRune(char)
+	}
+	return sb.String()
+}
@@ -0,0 +1,115 @@
+package ini_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ncpa0cpl/ini"
+)
+
+func TestApplyPatch(t *testing.T) {
+	expect := expect(t)
+
+	doc := ini.Parse(`; user settings
+name=app
+
+[db]
+host=localhost
+prot=5432 ; db port
+user=root
+
+[cache]
+ttl=60
+`)
+
+	patchJson := `[
+		{"op": "set", "path": "name", "value": "my-app"},
+		{"op": "rename", "path": "db.prot", "to": "db.port"},
+		{"op": "delete", "path": "db.user"},
+		{"op": "rename", "path": "cache.ttl", "to": "db.cache_ttl"},
+		{"op": "set", "path": "db.pool.size", "value": "8"}
+	]`
+
+	var patch ini.Patch
+	expect(json.Unmarshal([]byte(patchJson), &patch)).NoErr()
+
+	expect(doc.Apply(patch)).NoErr()
+	expect(doc.ToString()).ToBe(`; user settings
+name=my-app
+
+[db]
+host=localhost
+port=5432 ; db port
+
+cache_ttl=60
+
+[db.pool]
+size=8
+`)
+}
+
+func TestApplyPatchAtomic(t *testing.T) {
+	expect := expect(t)
+
+	docStr := `name=app
+
+[db]
+host=localhost
+port=5432
+`
+	doc := ini.Parse(docStr)
+	db := doc.Section("db")
+
+	err := doc.Apply(ini.Patch{
+		{Op: ini.PatchSet, Path: "db.host", Value: "example.com"},
+		{Op: ini.PatchDelete, Path: "db.user"},
+	})
+	expect(err.Error()).ToBe("patch operation 1 (delete 'db.user'): key does not exist")
+	expect(errors.Is(err, ini.ErrPatchKeyNotFound)).ToBe(true)
+	expect(doc.ToString()).ToBe(docStr)
+
+	err = doc.Apply(ini.Patch{
+		{Op: ini.PatchRename, Path: "db.host", To: "db.port"},
+	})
+	expect(err.Error()).ToBe("patch operation 0 (rename 'db.host'): key already exists: 'db.port'")
+	expect(errors.Is(err, ini.ErrPatchKeyExists)).ToBe(true)
+
+	err = doc.Apply(ini.Patch{
+		{Op: "replace", Path: "name"},
+	})
+	expect(err.Error()).ToBe("patch operation 0 (replace 'name'): unknown operation")
+	expect(errors.Is(err, ini.ErrPatchUnknownOp)).ToBe(true)
+
+	err = doc.Apply(ini.Patch{
+		{Op: ini.PatchSet, Path: "db.", Value: "x"},
+	})
+	expect(err.Error()).ToBe("patch operation 0 (set 'db.'): invalid key: ''")
+	expect(errors.Is(err, ini.ErrPatchInvalidKey)).ToBe(true)
+	expect(doc.ToString()).ToBe(docStr)
+
+	// previously retrieved sections still belong to the document after a patch
+	expect(doc.Apply(ini.Patch{{Op: ini.PatchSet, Path: "db.host", Value: "example.com"}})).NoErr()
+	expect(db.Get("host")).ToBe("example.com")
+}
+
+func TestApplyPatchDottedKeys(t *testing.T) {
+	expect := expect(t)
+
+	doc := ini.Parse("log.level=info\n\n[server]\nhttp.port=80\n")
+
+	patchJson := `[
+		{"op": "set", "path": "log\\.level", "value": "debug"},
+		{"op": "rename", "path": "server.http\\.port", "to": "server.http\\.listen"},
+		{"op": "set", "path": "back\\\\slash", "value": "x"}
+	]`
+
+	var patch ini.Patch
+	expect(json.Unmarshal([]byte(patchJson), &patch)).NoErr()
+
+	expect(doc.Apply(patch)).NoErr()
+	expect(doc.Get("log.level")).ToBe("debug")
+	expect(doc.Get(`back\slash`)).ToBe("x")
+	expect(doc.Section("server").Keys()).ToBe([]string{"http.listen"})
+	expect(doc.HasSection("log")).ToBe(false)
+}
@@ -5,6 +5,9 @@ import "strings"
 const DISALLOWED_KEY_CHARS = "?{}|&~![()^\n"
 
 func isKeyValid(key string) bool {
+	// brackets are allowed at the end of a key to mark a list (e.x. `host[]`)
+	key = strings.TrimSuffix(key, "[]")
+
 	if key == "" {
 		return false
 	}
@@ -15,3 +18,7 @@ func isKeyValid(key string) bool {
 
 	return true
 }
+
+func isListKey(key string) bool {
+	return len(key) > 2 && strings.HasSuffix(key, "[]")
+}